@@ -0,0 +1,474 @@
+package baiduocr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// AipOCR is a client of the OCR services of Baidu AI open platform
+	// (aip.baidubce.com). Unlike OCR, it authenticates with an access token
+	// obtained from the API key and secret key of your application. The token
+	// is fetched on first use and cached until it expires, so use the same
+	// *AipOCR across calls.
+	AipOCR struct {
+		// Set API key of your Baidu AI application
+		APIKey string
+		// Set secret key of your Baidu AI application
+		SecretKey string
+		// Set access token entrypoint, default is https://aip.baidubce.com/oauth/2.0/token
+		TokenPath string
+		// Set API entrypoint prefix, default is https://aip.baidubce.com/rest/2.0/ocr/v1/
+		APIPath string
+		// Set request timeout in milliseconds (ms), default is 5000, set to -1 means no timeout
+		TimeoutInMilliseconds int64
+		// Set what the timeout limits, default is TimeoutTotal, the whole request
+		TimeoutMode TimeoutMode
+		// Set HTTP client to send requests with, default is a new client for each request
+		HTTPClient *http.Client
+		// Set a function to receive an audit record of every OCR request before it is sent
+		AuditFunc func(AuditRecord)
+		// Set signer of every request, such as a BCESigner for the signed BCE API, default is nil.
+		// Signed requests are sent without an access token, so APIKey and SecretKey are not used.
+		Signer Signer
+		// Set endpoints to fall back to when the quota of an endpoint is
+		// exhausted, for example {"accurate_basic": "general_basic"}. The
+		// image is submitted again to the fallback endpoint, which may have a
+		// fallback itself, unless the context of the call is done. The
+		// endpoint which served the result is in ResultMeta.Endpoint.
+		Fallbacks map[string]string
+		// Set maximum duration of a call from start to end, including decoding, preprocessing and
+		// encoding the image besides the requests, default is no limit. Preprocessing stops between
+		// its steps once it is exceeded. TimeoutInMilliseconds still limits the requests within it.
+		OperationTimeout time.Duration
+		// Set maximum number of goroutines preprocessing an image, default is GOMAXPROCS, see
+		// OCR.PreprocessParallelism
+		PreprocessParallelism int
+		// Set a function to log warnings with, such as log.Printf, of large request bodies and of PNG
+		// images with ICC profiles other than sRGB, default is nil, meaning nothing is logged
+		LogFunc func(format string, v ...interface{})
+		// Set size in bytes of an OCR request body above which a warning is logged with LogFunc,
+		// without failing the request, default is 0, meaning no warning
+		PayloadWarnBytes int64
+
+		mutex          sync.Mutex
+		token          string
+		tokenExpiresAt time.Time
+	}
+
+	aipRect struct {
+		Left   int `json:"left"`
+		Top    int `json:"top"`
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}
+
+	aipTokenRet struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	aipOCRRet struct {
+		ErrorCode   int             `json:"error_code"`
+		ErrorMsg    string          `json:"error_msg"`
+		Language    json.RawMessage `json:"language"`
+		WordsResult []struct {
+			Words            string        `json:"words"`
+			Location         aipRect       `json:"location"`
+			VertexesLocation []image.Point `json:"vertexes_location"`
+			Chars            []struct {
+				Char     string  `json:"char"`
+				Location aipRect `json:"location"`
+			} `json:"chars"`
+			Probability *struct {
+				Average float64 `json:"average"`
+			} `json:"probability"`
+		} `json:"words_result"`
+		ParagraphsResult []struct {
+			WordsResultIdx []int `json:"words_result_idx"`
+		} `json:"paragraphs_result"`
+	}
+)
+
+const (
+	_AIP_TOKEN_PATH = "https://aip.baidubce.com/oauth/2.0/token"
+	_AIP_API_PATH   = "https://aip.baidubce.com/rest/2.0/ocr/v1/"
+
+	// error codes meaning the access token must be fetched again
+	_AIP_INVALID_TOKEN = 110
+	_AIP_EXPIRED_TOKEN = 111
+
+	// error codes meaning the daily or total quota is exhausted
+	_AIP_DAILY_LIMIT = 17
+	_AIP_TOTAL_LIMIT = 19
+)
+
+// endpoints accepting the language_type and detect_language parameters
+var aipLanguageEndpoints = map[string]bool{
+	"general_basic":  true,
+	"general":        true,
+	"accurate_basic": true,
+	"accurate":       true,
+}
+
+// endpoints accepting the probability parameter
+var aipConfidenceEndpoints = map[string]bool{
+	"general_basic":  true,
+	"general":        true,
+	"accurate_basic": true,
+	"accurate":       true,
+	"handwriting":    true,
+}
+
+// endpoints accepting the vertexes_location and recognize_granularity
+// parameters
+var aipVertexesEndpoints = map[string]bool{
+	"general":  true,
+	"accurate": true,
+}
+
+// Create a client of Baidu AI open platform OCR services, sending requests
+// with an HTTP client configured with options.
+func NewAipOCR(apiKey, secretKey string, options ...ClientOption) *AipOCR {
+	return &AipOCR{APIKey: apiKey, SecretKey: secretKey, HTTPClient: newHTTPClient(options)}
+}
+
+// Read printed text from JPEG/PNG image with the general_basic endpoint.
+func (aip *AipOCR) GeneralBasic(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
+	return aip.parse("general_basic", imageBytes, options...)
+}
+
+// Read printed text from JPEG/PNG image with the accurate_basic endpoint,
+// which is slower and has a smaller free quota than general_basic but
+// recognizes small or blurry text better.
+func (aip *AipOCR) AccurateBasic(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
+	return aip.parse("accurate_basic", imageBytes, options...)
+}
+
+// Read handwritten Chinese and English text from JPEG/PNG image with the
+// handwriting endpoint. The printed-text model of general_basic tends to drop
+// or misread joined and cursive strokes, while the handwriting model is
+// trained on written text and copes with them much better. On printed text
+// it is no better than general_basic, so use it only for written input.
+// Language options are ignored, the endpoint detects the language itself.
+func (aip *AipOCR) Handwriting(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
+	return aip.parse("handwriting", imageBytes, options...)
+}
+
+func (aip *AipOCR) parse(endpoint string, imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
+	var words []Word
+	words, _, err = aip.ParseDetailed(endpoint, imageBytes, options...)
+	results = wordTexts(words)
+	return
+}
+
+// Read text from JPEG/PNG image with the named endpoint, such as general or
+// accurate, returning each recognized word with its position if the endpoint
+// reports it, and information about the recognition.
+func (aip *AipOCR) ParseDetailed(endpoint string, imageBytes []byte, options ...BaiduOCROption) (words []Word, meta ResultMeta, err error) {
+	opts, timedOut, cancel := aip.newOptions(options).withOperationTimeout(aip.OperationTimeout)
+	defer cancel()
+	defer func() { err = timedOut(err) }()
+	warnICCProfile(aip.LogFunc, imageBytes)
+	words, meta, err = opts.withFallbackPreprocessing(func(opts baiduOCROption) ([]Word, ResultMeta, error) {
+		return aip.parseDetailed(endpoint, imageBytes, opts)
+	})
+	meta.Timing = opts.timingResult()
+	meta.ICCProfile, _ = pngICCProfile(imageBytes)
+	return
+}
+
+func (aip *AipOCR) parseDetailed(endpoint string, imageBytes []byte, opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+	imageBytes, err = toSubmitted(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	err = checkBlank(imageBytes, opts)
+	if err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	for {
+		seen[endpoint] = true
+		words, meta, err = aip.recognize(endpoint, imageBytes, opts)
+		next, ok := aip.Fallbacks[endpoint]
+		if !isQuotaError(err) || !ok || seen[next] {
+			break
+		}
+		if opts.context != nil && opts.context.Err() != nil {
+			break
+		}
+		endpoint = next
+	}
+	if err == nil && opts.autoGranularity && !opts.chars && aipVertexesEndpoints[endpoint] && mergedChars(words) {
+		charOpts := opts
+		charOpts.chars = true
+		words, meta, err = aip.recognize(endpoint, imageBytes, charOpts)
+	}
+	if err != nil {
+		return
+	}
+	words, err = opts.postprocess(words, &meta)
+	return
+}
+
+func (aip *AipOCR) recognize(endpoint string, imageBytes []byte, opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+	meta.Endpoint = endpoint
+	meta.ImageSize = imageSize(imageBytes)
+	meta.ImageSHA256 = opts.imageHash(imageBytes)
+	if aipVertexesEndpoints[endpoint] {
+		meta.Granularity = _GRANULARITY_BIG
+		if opts.chars {
+			meta.Granularity = _GRANULARITY_SMALL
+		}
+	}
+	form := newAipForm(endpoint, imageBytes, opts)
+	var ret aipOCRRet
+	ret, meta.Headers, err = aip.post(endpoint, form, opts)
+	if err == nil && (ret.ErrorCode == _AIP_INVALID_TOKEN || ret.ErrorCode == _AIP_EXPIRED_TOKEN) {
+		aip.resetToken()
+		ret, meta.Headers, err = aip.post(endpoint, form, opts)
+	}
+	if err != nil {
+		return
+	}
+	words, err = ret.result(&meta)
+	err = opts.formatted(err)
+	return
+}
+
+// newAipForm returns the form submitting the image to endpoint, with the
+// parameters the endpoint accepts for the options.
+func newAipForm(endpoint string, imageBytes []byte, opts baiduOCROption) imageForm {
+	params := url.Values{}
+	if aipLanguageEndpoints[endpoint] {
+		params.Set("language_type", opts.languageType)
+		if opts.detectLanguage || opts.detectScript {
+			params.Set("detect_language", "true")
+		}
+		if opts.baiduLines {
+			params.Set("paragraph", "true")
+		}
+	}
+	if opts.confidence && aipConfidenceEndpoints[endpoint] {
+		params.Set("probability", "true")
+	}
+	if aipVertexesEndpoints[endpoint] {
+		params.Set("vertexes_location", "true")
+		if opts.chars {
+			params.Set("recognize_granularity", _GRANULARITY_SMALL)
+		}
+	}
+	return opts.newImageForm(params, imageBytes)
+}
+
+// Read text from JPEG/PNG image with the named endpoint and aggregate the
+// confidence of the words into a rows×cols heatmap of the image, see
+// Heatmap. The confidence is requested with RequestConfidence, so this only
+// works with endpoints accepting the probability parameter and locating
+// text, that is general, accurate and handwriting. Otherwise, or if Baidu
+// reports no confidence at all, the heatmap is nil.
+func (aip *AipOCR) ParseHeatmap(endpoint string, imageBytes []byte, rows, cols int, options ...BaiduOCROption) (heatmap *Heatmap, err error) {
+	var words []Word
+	var meta ResultMeta
+	words, meta, err = aip.ParseDetailed(endpoint, imageBytes, append(options[:len(options):len(options)], RequestConfidence())...)
+	if err != nil {
+		return
+	}
+	heatmap = newHeatmap(words, meta.ImageSize, rows, cols)
+	return
+}
+
+// Same as OCR.ParseLines, with the named endpoint.
+func (aip *AipOCR) ParseLines(endpoint string, imageBytes []byte, options ...BaiduOCROption) (lines [][]string, err error) {
+	var words []Word
+	var meta ResultMeta
+	words, meta, err = aip.ParseDetailed(endpoint, imageBytes, options...)
+	if err != nil {
+		return
+	}
+	lines = lineTexts(words, meta, newOptions(options))
+	return
+}
+
+func (loc aipRect) rect() image.Rectangle {
+	return image.Rect(loc.Left, loc.Top, loc.Left+loc.Width, loc.Top+loc.Height)
+}
+
+func isQuotaError(err error) bool {
+	var ocrErr OCRError
+	return errors.As(err, &ocrErr) && (ocrErr.Code == _AIP_DAILY_LIMIT || ocrErr.Code == _AIP_TOTAL_LIMIT)
+}
+
+// language returns the language reported in the response, if any. Baidu
+// reports it either as a name or as a number, -1 meaning unknown.
+func (ret aipOCRRet) language() string {
+	var name string
+	if json.Unmarshal(ret.Language, &name) == nil {
+		return name
+	}
+	var code json.Number
+	if json.Unmarshal(ret.Language, &code) == nil && code != "-1" {
+		return code.String()
+	}
+	return ""
+}
+
+func (aip *AipOCR) post(endpoint string, form imageForm, opts baiduOCROption) (ret aipOCRRet, header http.Header, err error) {
+	var req *http.Request
+	req, err = aip.newRequest(endpoint, form, opts, aip.AuditFunc)
+	if err != nil {
+		return
+	}
+
+	var body []byte
+	done := opts.track(networkPhase)
+	body, header, err = doRequest(aip.HTTPClient, aip.TimeoutInMilliseconds, aip.TimeoutMode, aip.Signer, req)
+	done()
+	if err != nil {
+		return
+	}
+	body, err = opts.decodeUTF8(body)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(body, &ret)
+	return
+}
+
+// newRequest returns the request submitting the form to endpoint, with the
+// access token unless Signer is set. The request is passed to audit, if not
+// nil.
+func (aip *AipOCR) newRequest(endpoint string, form imageForm, opts baiduOCROption, audit func(AuditRecord)) (req *http.Request, err error) {
+	path := aip.APIPath
+	if len(path) == 0 {
+		path = _AIP_API_PATH
+	}
+	path += endpoint
+	if aip.Signer == nil {
+		var token string
+		token, err = aip.accessToken(opts)
+		if err != nil {
+			return
+		}
+		path += "?access_token=" + url.QueryEscape(token)
+	}
+	requestID := opts.requestID
+	if requestID == "" && audit != nil {
+		requestID = newRequestID()
+	}
+	// the path without the access token
+	endpointPath := strings.SplitN(path, "?", 2)[0]
+	if audit != nil {
+		record := newAuditRecord(endpointPath, form, opts)
+		record.RequestID = requestID
+		audit(record)
+	}
+
+	req, err = form.newRequest(path)
+	if err != nil {
+		return
+	}
+	warnLargePayload(aip.LogFunc, aip.PayloadWarnBytes, endpointPath, req.ContentLength)
+	if opts.context != nil {
+		req = req.WithContext(opts.context)
+	}
+	opts.setHeaders(req)
+	if requestID != "" {
+		req.Header.Set("X-Request-Id", requestID)
+	}
+	return
+}
+
+// PrefetchToken fetches and caches the access token ahead of the first
+// request, such as at startup, which otherwise pays for the fetch. It does
+// nothing if the cached token is still valid, or if Signer is set. Calls are
+// safe to make concurrently with each other and with requests, which wait for
+// the fetch in flight instead of starting another. It returns the error of the
+// fetch, such as a wrong API key.
+func (aip *AipOCR) PrefetchToken(ctx context.Context) error {
+	if aip.Signer != nil {
+		return nil
+	}
+	_, err := aip.accessToken(newOptions([]BaiduOCROption{SetContext(ctx)}))
+	return err
+}
+
+// accessToken returns the cached access token, fetching a new one if there
+// is none or it is about to expire.
+func (aip *AipOCR) accessToken(opts baiduOCROption) (token string, err error) {
+	aip.mutex.Lock()
+	defer aip.mutex.Unlock()
+	if aip.token != "" && currentClock.now().Before(aip.tokenExpiresAt) {
+		token = aip.token
+		return
+	}
+
+	path := aip.TokenPath
+	if len(path) == 0 {
+		path = _AIP_TOKEN_PATH
+	}
+	// the credentials are sent in the body rather than the query, which
+	// errors of the request print
+	var req *http.Request
+	req, err = http.NewRequest("POST", path, strings.NewReader(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {aip.APIKey},
+		"client_secret": {aip.SecretKey},
+	}.Encode()))
+	if err != nil {
+		return
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	if opts.context != nil {
+		req = req.WithContext(opts.context)
+	}
+	opts.setHeaders(req)
+
+	var body []byte
+	done := opts.track(networkPhase)
+	body, _, err = doRequest(aip.HTTPClient, aip.TimeoutInMilliseconds, aip.TimeoutMode, aip.Signer, req)
+	done()
+	if err != nil {
+		return
+	}
+	body, err = opts.decodeUTF8(body)
+	if err != nil {
+		return
+	}
+	var ret aipTokenRet
+	err = json.Unmarshal(body, &ret)
+	if err != nil {
+		return
+	}
+	if ret.AccessToken == "" {
+		msg := "BaiduOCR failed to get access token."
+		if ret.Error != "" {
+			msg += fmt.Sprintf(" reason: %s (%s)", ret.ErrorDescription, ret.Error)
+		}
+		err = errors.New(msg)
+		return
+	}
+	// refresh one minute early so that a token never expires in flight
+	aip.token = ret.AccessToken
+	aip.tokenExpiresAt = currentClock.now().Add(time.Duration(ret.ExpiresIn)*time.Second - time.Minute)
+	token = aip.token
+	return
+}
+
+func (aip *AipOCR) resetToken() {
+	aip.mutex.Lock()
+	aip.token = ""
+	aip.mutex.Unlock()
+}
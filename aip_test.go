@@ -0,0 +1,543 @@
+package baiduocr_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+// newAipTestServer starts a fake Baidu AI server. Every token request returns
+// a new token, and handler answers OCR requests made with the endpoint name
+// and the current token.
+func newAipTestServer(t *testing.T, handler func(endpoint, token string, r *http.Request) interface{}) (*baiduocr.AipOCR, *int32) {
+	var tokens int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "ak" || r.FormValue("client_secret") != "sk" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client", "error_description": "unknown client id"})
+			return
+		}
+		n := atomic.AddInt32(&tokens, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": fmt.Sprintf("token%d", n), "expires_in": 2592000})
+	})
+	mux.HandleFunc("/ocr/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(handler(strings.TrimPrefix(r.URL.Path, "/ocr/"), r.URL.Query().Get("access_token"), r))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	aip := baiduocr.NewAipOCR("ak", "sk")
+	aip.TokenPath = server.URL + "/token"
+	aip.APIPath = server.URL + "/ocr/"
+	return aip, &tokens
+}
+
+func wordsResult(words ...string) interface{} {
+	var ret []map[string]string
+	for _, w := range words {
+		ret = append(ret, map[string]string{"words": w})
+	}
+	return map[string]interface{}{"words_result": ret, "words_result_num": len(ret)}
+}
+
+func TestAipHandwriting(t *testing.T) {
+	aip, tokens := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		if endpoint != "handwriting" {
+			t.Errorf("endpoint = %s, want handwriting", endpoint)
+		}
+		if r.FormValue("image") == "" {
+			t.Error("image is missing")
+		}
+		if r.FormValue("language_type") != "" {
+			t.Error("handwriting does not take language_type")
+		}
+		return wordsResult("手写", "文字")
+	})
+	for i := 0; i < 2; i++ {
+		results, err := aip.Handwriting(fakeJPEG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(results, ","); got != "手写,文字" {
+			t.Errorf("got %s", got)
+		}
+	}
+	if *tokens != 1 {
+		t.Errorf("fetched %d tokens, want the token to be cached", *tokens)
+	}
+}
+
+func TestAipRefreshesInvalidToken(t *testing.T) {
+	aip, tokens := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		if token == "token1" {
+			return map[string]interface{}{"error_code": 110, "error_msg": "Access token invalid or no longer valid"}
+		}
+		return wordsResult("ok")
+	})
+	results, err := aip.GeneralBasic(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		t.Errorf("got %v", results)
+	}
+	if *tokens != 2 {
+		t.Errorf("fetched %d tokens, want 2", *tokens)
+	}
+}
+
+func TestAipPrefetchToken(t *testing.T) {
+	aip, tokens := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		if token != "token1" {
+			t.Errorf("token = %s, want the prefetched token1", token)
+		}
+		return wordsResult("ok")
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := aip.PrefetchToken(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if *tokens != 1 {
+		t.Errorf("fetched %d tokens, want 1", *tokens)
+	}
+	if _, err := aip.GeneralBasic(fakeJPEG); err != nil {
+		t.Fatal(err)
+	}
+	if *tokens != 1 {
+		t.Errorf("fetched %d tokens, want the prefetched one used", *tokens)
+	}
+
+	wrong := baiduocr.NewAipOCR("ak", "wrong")
+	wrong.TokenPath = aip.TokenPath
+	if err := wrong.PrefetchToken(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown client id") {
+		t.Errorf("got %v, want the error of the fetch", err)
+	}
+}
+
+func TestAipErrors(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return map[string]interface{}{"error_code": 17, "error_msg": "Open api daily request limit reached"}
+	})
+	if _, err := aip.AccurateBasic(fakeJPEG); err == nil || !strings.Contains(err.Error(), "daily request limit") {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := aip.GeneralBasic([]byte("not an image")); err == nil {
+		t.Error("expected error for unrecognized image")
+	}
+	wrong := baiduocr.NewAipOCR("ak", "wrong")
+	wrong.TokenPath, wrong.APIPath = aip.TokenPath, aip.APIPath
+	if _, err := wrong.GeneralBasic(fakeJPEG); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestAipDetectedLanguage(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		ret := wordsResult("hello").(map[string]interface{})
+		if r.FormValue("detect_language") == "true" {
+			ret["language"] = "ENG"
+		}
+		return ret
+	})
+	words, meta, err := aip.ParseDetailed("general_basic", fakeJPEG, baiduocr.DetectLanguage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || meta.DetectedLanguage != "ENG" {
+		t.Errorf("got %v, %+v", words, meta)
+	}
+	if _, meta, _ = aip.ParseDetailed("general_basic", fakeJPEG); meta.DetectedLanguage != "" {
+		t.Errorf("got language %q without DetectLanguage", meta.DetectedLanguage)
+	}
+}
+
+func TestAipFallbacks(t *testing.T) {
+	var called []string
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		called = append(called, endpoint)
+		if endpoint == "accurate_basic" {
+			return map[string]interface{}{"error_code": 17, "error_msg": "Open api daily request limit reached"}
+		}
+		return wordsResult("ok")
+	})
+	aip.Fallbacks = map[string]string{"accurate_basic": "general_basic"}
+	words, meta, err := aip.ParseDetailed("accurate_basic", fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || meta.Endpoint != "general_basic" {
+		t.Errorf("got %v from %s, want general_basic", words, meta.Endpoint)
+	}
+	if got := strings.Join(called, ","); got != "accurate_basic,general_basic" {
+		t.Errorf("called %s", got)
+	}
+
+	aip.Fallbacks = map[string]string{"accurate_basic": "accurate_basic"}
+	if _, _, err := aip.ParseDetailed("accurate_basic", fakeJPEG); err == nil {
+		t.Error("want quota error with a fallback cycle")
+	}
+}
+
+func TestAipParseHeatmap(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 100, 40))
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		if r.FormValue("probability") != "true" {
+			return wordsResult("no confidence")
+		}
+		return map[string]interface{}{"words_result": []interface{}{
+			map[string]interface{}{
+				"words":       "sure",
+				"location":    map[string]int{"left": 0, "top": 0, "width": 40, "height": 15},
+				"probability": map[string]float64{"average": 0.9},
+			},
+			map[string]interface{}{
+				"words":       "unsure",
+				"location":    map[string]int{"left": 30, "top": 25, "width": 70, "height": 15},
+				"probability": map[string]float64{"average": 0.4},
+			},
+		}}
+	})
+	heatmap, err := aip.ParseHeatmap("general", buf.Bytes(), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heatmap == nil {
+		t.Fatal("heatmap is nil")
+	}
+	if got, want := fmt.Sprint(heatmap.Values), "[0.9 -1 0.4 0.4]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if heatmap, err = aip.ParseHeatmap("webimage", buf.Bytes(), 2, 2); heatmap != nil || err != nil {
+		t.Errorf("got %v, %v, want nil heatmap", heatmap, err)
+	}
+}
+
+func TestAipQuad(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		result := map[string]interface{}{
+			"words":    "tilted",
+			"location": map[string]int{"left": 10, "top": 0, "width": 30, "height": 40},
+		}
+		if r.FormValue("vertexes_location") == "true" {
+			result["vertexes_location"] = []map[string]int{{"x": 10, "y": 10}, {"x": 30, "y": 0}, {"x": 40, "y": 30}, {"x": 20, "y": 40}}
+		}
+		return map[string]interface{}{"words_result": []interface{}{result}}
+	})
+	for endpoint, want := range map[string]string{
+		"general":       "[(10,10) (30,0) (40,30) (20,40)]",
+		"general_basic": "[(10,0) (40,0) (40,40) (10,40)]",
+	} {
+		wantAngle := 0.0
+		if endpoint == "general" {
+			wantAngle = math.Atan(0.5) * 180 / math.Pi
+		}
+		words, _, err := aip.ParseDetailed(endpoint, fakeJPEG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(words[0].Quad); got != want {
+			t.Errorf("%s: quad = %s, want %s", endpoint, got, want)
+		}
+		if got := words[0].Rect; got != image.Rect(10, 0, 40, 40) {
+			t.Errorf("%s: rect = %v", endpoint, got)
+		}
+		if got := words[0].Angle(); math.Abs(got-wantAngle) > 1e-9 {
+			t.Errorf("%s: angle = %v, want %v", endpoint, got, wantAngle)
+		}
+	}
+	for _, quad := range []struct {
+		quad  [4]image.Point
+		angle float64
+	}{
+		{[4]image.Point{{0, 10}, {10, 0}, {20, 10}, {10, 20}}, 45},
+		{[4]image.Point{{10, 0}, {20, 10}, {10, 20}, {0, 10}}, -45},
+		{[4]image.Point{{40, 20}, {0, 20}, {0, 0}, {40, 0}}, 180},
+		{[4]image.Point{}, 0},
+	} {
+		if got := (baiduocr.Word{Quad: quad.quad}).Angle(); math.Abs(got-quad.angle) > 1e-9 {
+			t.Errorf("angle of %v = %v, want %v", quad.quad, got, quad.angle)
+		}
+	}
+}
+
+func TestAipRequestChars(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		result := map[string]interface{}{"words": "中文"}
+		if r.FormValue("recognize_granularity") == "small" {
+			result["chars"] = []interface{}{
+				map[string]interface{}{"char": "中", "location": map[string]int{"left": 0, "top": 0, "width": 10, "height": 10}},
+				map[string]interface{}{"char": "文", "location": map[string]int{"left": 10, "top": 0, "width": 10, "height": 10}},
+			}
+		}
+		return map[string]interface{}{"words_result": []interface{}{result}}
+	})
+	words, _, err := aip.ParseDetailed("general", fakeJPEG, baiduocr.RequestChars())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(words[0].Tokens()); got != "[中 文]" {
+		t.Errorf("tokens = %s", got)
+	}
+	if got := words[0].Chars[1].Rect; got != image.Rect(10, 0, 20, 10) {
+		t.Errorf("rect = %v", got)
+	}
+	if got := fmt.Sprint(words[0].Positioned()); got != "[{中 (5,5) (0,0)-(10,10)} {文 (15,5) (10,0)-(20,10)}]" {
+		t.Errorf("positioned = %s", got)
+	}
+	for _, endpoint := range []string{"general", "general_basic"} {
+		opts := []baiduocr.BaiduOCROption{baiduocr.RequestChars()}
+		if endpoint == "general" {
+			opts = nil
+		}
+		words, _, err := aip.ParseDetailed(endpoint, fakeJPEG, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(words[0].Chars) != 0 || fmt.Sprint(words[0].Tokens()) != "[中文]" {
+			t.Errorf("%s: chars = %v, want none", endpoint, words[0].Chars)
+		}
+		if got := fmt.Sprint(words[0].Positioned()); got != "[{中文 (0,0) (0,0)-(0,0)}]" {
+			t.Errorf("%s: positioned = %s, want the word without a box", endpoint, got)
+		}
+	}
+	word := baiduocr.Word{Text: "ab", Rect: image.Rect(10, 10, 21, 20)}
+	if got := fmt.Sprint(word.Positioned()); got != "[{ab (15,15) (10,10)-(21,20)}]" {
+		t.Errorf("positioned = %s, want the word with its box", got)
+	}
+}
+
+func TestAipSmartFormat(t *testing.T) {
+	var submitted string
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		data, err := base64.StdEncoding.DecodeString(r.FormValue("image"))
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		submitted = http.DetectContentType(data)
+		return wordsResult("ok")
+	})
+	screenshot := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	draw.Draw(screenshot, screenshot.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(screenshot, image.Rect(8, 8, 40, 24), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	random := rand.New(rand.NewSource(1))
+	for i := range photo.Pix {
+		photo.Pix[i] = uint8(random.Intn(256))
+		if i%4 == 3 {
+			photo.Pix[i] = 255
+		}
+	}
+	for _, test := range []struct {
+		image   []byte
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{encodePNG(t, screenshot), nil, "image/jpeg"},
+		{encodePNG(t, screenshot), []baiduocr.BaiduOCROption{baiduocr.SmartFormat()}, "image/png"},
+		{encodePNG(t, photo), []baiduocr.BaiduOCROption{baiduocr.SmartFormat()}, "image/jpeg"},
+		{fakeJPEG, []baiduocr.BaiduOCROption{baiduocr.SmartFormat()}, "image/jpeg"},
+	} {
+		_, meta, err := aip.ParseDetailed("general_basic", test.image, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if submitted != test.want {
+			t.Errorf("submitted %s, want %s", submitted, test.want)
+		}
+		if test.image[1] == 'P' && meta.ImageSize != image.Pt(64, 32) {
+			t.Errorf("image size = %v", meta.ImageSize)
+		}
+	}
+}
+
+func TestAipRedactsCredentials(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult("ok")
+	})
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tokenOnly := baiduocr.NewAipOCR("ak", "TOPSECRET")
+	tokenOnly.TokenPath = closed.URL + "/token"
+	if _, err := tokenOnly.GeneralBasic(fakeJPEG); err == nil || strings.Contains(err.Error(), "TOPSECRET") {
+		t.Errorf("got %v, want an error without the secret key", err)
+	}
+
+	if err := aip.PrefetchToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	aip.APIPath = closed.URL + "/ocr/"
+	_, err := aip.GeneralBasic(fakeJPEG)
+	if err == nil || strings.Contains(err.Error(), "token1") || !strings.Contains(err.Error(), "access_token=REDACTED") {
+		t.Errorf("got %v, want an error with the access token redacted", err)
+	}
+}
+
+func TestAipTokenInBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("query = %q, want the credentials in the body", r.URL.RawQuery)
+		}
+		if r.PostFormValue("client_secret") != "sk" {
+			t.Errorf("client_secret = %q", r.PostFormValue("client_secret"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 2592000})
+	}))
+	defer server.Close()
+	aip := baiduocr.NewAipOCR("ak", "sk")
+	aip.TokenPath = server.URL
+	if err := aip.PrefetchToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAipAuditFunc(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		if r.Header.Get("X-Request-Id") == "" {
+			t.Error("request ID is missing")
+		}
+		return wordsResult("ok")
+	})
+	var records []baiduocr.AuditRecord
+	aip.AuditFunc = func(record baiduocr.AuditRecord) { records = append(records, record) }
+	if _, err := aip.GeneralBasic(fakeJPEG); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	record := records[0]
+	if !strings.HasSuffix(record.Endpoint, "/ocr/general_basic") || strings.Contains(record.Endpoint, "token") {
+		t.Errorf("endpoint = %s, want the path without the token", record.Endpoint)
+	}
+	if record.RequestID == "" || !strings.HasPrefix(record.Params.Get("image"), "[redacted ") {
+		t.Errorf("got %+v", record)
+	}
+}
+
+func TestAipSmartFormatTransparent(t *testing.T) {
+	var submitted image.Image
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		data, err := base64.StdEncoding.DecodeString(r.FormValue("image"))
+		if err == nil {
+			submitted, _, err = image.Decode(bytes.NewReader(data))
+		}
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		return wordsResult("ok")
+	})
+	// transparent but for a white square
+	src := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(src, image.Rect(8, 8, 24, 24), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	if _, err := aip.GeneralBasic(encodePNG(t, src), baiduocr.SmartFormat()); err != nil {
+		t.Fatal(err)
+	}
+	if submitted == nil {
+		t.Fatal("no image submitted")
+	}
+	if r, g, b, a := submitted.At(0, 0).RGBA(); r|g|b != 0 || a != 0xffff {
+		t.Errorf("transparent pixel = %v, want opaque black as in JPEG", submitted.At(0, 0))
+	}
+	if g := gray(submitted, 16, 16); g != 255 {
+		t.Errorf("white pixel = %d", g)
+	}
+}
+
+func TestAipTokenExpiry(t *testing.T) {
+	clock := baiduocr.UseFakeClock(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	aip, tokens := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult(token)
+	})
+	parse := func() string {
+		results, err := aip.GeneralBasic(fakeJPEG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(results)
+	}
+	if got := parse(); got != "[token1]" {
+		t.Errorf("got %s, want [token1]", got)
+	}
+	// tokens last 30 days and are refreshed a minute early
+	clock.Advance(30*24*time.Hour - 2*time.Minute)
+	if got := parse(); got != "[token1]" {
+		t.Errorf("got %s, want [token1] before expiry", got)
+	}
+	clock.Advance(time.Minute)
+	if got := parse(); got != "[token2]" || atomic.LoadInt32(tokens) != 2 {
+		t.Errorf("got %s after %d tokens, want [token2]", got, *tokens)
+	}
+}
+
+func TestAutoGranularity(t *testing.T) {
+	var requests, width int32
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		atomic.AddInt32(&requests, 1)
+		result := map[string]interface{}{
+			"words":    "x7Kp",
+			"location": map[string]int{"left": 0, "top": 0, "width": int(atomic.LoadInt32(&width)), "height": 20},
+		}
+		if r.FormValue("recognize_granularity") == "small" {
+			var chars []interface{}
+			for i, c := range "x7Kp" {
+				chars = append(chars, map[string]interface{}{"char": string(c), "location": map[string]int{"left": i * 20, "top": 0, "width": 20, "height": 20}})
+			}
+			result["chars"] = chars
+		}
+		return map[string]interface{}{"words_result": []interface{}{result}}
+	})
+	for _, test := range []struct {
+		endpoint    string
+		width       int32
+		requests    int32
+		granularity string
+	}{
+		{"general", 80, 2, "small"},
+		{"general", 30, 1, "big"},
+		{"general_basic", 80, 1, ""},
+	} {
+		atomic.StoreInt32(&requests, 0)
+		atomic.StoreInt32(&width, test.width)
+		words, meta, err := aip.ParseDetailed(test.endpoint, fakeJPEG, baiduocr.AutoGranularity())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := atomic.LoadInt32(&requests); got != test.requests {
+			t.Errorf("%s %d: %d requests, want %d", test.endpoint, test.width, got, test.requests)
+		}
+		if meta.Granularity != test.granularity {
+			t.Errorf("%s %d: granularity %q, want %q", test.endpoint, test.width, meta.Granularity, test.granularity)
+		}
+		if chars := len(words[0].Chars); (test.granularity == "small") != (chars == 4) {
+			t.Errorf("%s %d: %d chars", test.endpoint, test.width, chars)
+		}
+	}
+}
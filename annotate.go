@@ -0,0 +1,171 @@
+package baiduocr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+const (
+	_DEFAULT_ANNOTATION_LINE_WIDTH = 2
+	_DEFAULT_ANNOTATION_TEXT_SCALE = 1
+)
+
+var defaultAnnotationColor = color.RGBA{255, 0, 0, 255}
+
+// AnnotationStyle is how ParseAndAnnotate draws the words over the image.
+type AnnotationStyle struct {
+	// Set color of the outlines of the words and of the background of their
+	// labels, default is red
+	BoxColor color.Color
+	// Set width in pixels of the outlines, default is 2
+	LineWidth int
+	// Set color of the text of the labels, default is white
+	TextColor color.Color
+	// Set size in pixels of a pixel of the font of the labels, whose
+	// characters are 5×7 pixels, default is 1
+	TextScale int
+	// Set function drawing the text of each word instead of the labels,
+	// called after its outline with the word positioned in dst. The labels
+	// are drawn above the words, or below them at the top of the image, with
+	// a built-in bitmap font of printable ASCII only, other characters such
+	// as Chinese being drawn as question marks; draw them with a font such as
+	// of golang.org/x/image/font instead, or set a function doing nothing to
+	// draw the outlines only.
+	DrawText func(dst draw.Image, word Word)
+}
+
+// Option to set the style of the annotated image of ParseAndAnnotate.
+func SetAnnotationStyle(style AnnotationStyle) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.annotationStyle = style }}
+}
+
+// Read text from JPEG/PNG image, like ParseDetailed, and write a copy of the
+// image with the words outlined by their Quad and labeled with their text to
+// w as PNG, such as to review the results by eye, see SetAnnotationStyle. The outlines are scaled from
+// the submitted image to the original one, so they only line up if the
+// preprocessing options do not crop or rotate the image. Words without a
+// bounding box are not drawn. The texts are returned even if the annotated
+// image fails to be written.
+func (ocr OCR) ParseAndAnnotate(imageBytes []byte, w io.Writer, options ...BaiduOCROption) (results []string, err error) {
+	var words []Word
+	var meta ResultMeta
+	words, meta, err = ocr.ParseDetailed(imageBytes, options...)
+	if err != nil {
+		return
+	}
+	results = wordTexts(words)
+	err = annotate(w, imageBytes, words, meta, ocr.newOptions(options))
+	return
+}
+
+// Same as OCR.ParseAndAnnotate, with the named endpoint, which must locate
+// text, like general or accurate, for words to be outlined.
+func (aip *AipOCR) ParseAndAnnotate(endpoint string, imageBytes []byte, w io.Writer, options ...BaiduOCROption) (results []string, err error) {
+	var words []Word
+	var meta ResultMeta
+	words, meta, err = aip.ParseDetailed(endpoint, imageBytes, options...)
+	if err != nil {
+		return
+	}
+	results = wordTexts(words)
+	err = annotate(w, imageBytes, words, meta, newOptions(options))
+	return
+}
+
+// annotate writes the image with the words drawn over it to w as PNG.
+func annotate(w io.Writer, imageBytes []byte, words []Word, meta ResultMeta, opts baiduOCROption) error {
+	img, err := decodeImage(imageBytes, opts)
+	if err != nil {
+		return err
+	}
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, img, bounds.Min, draw.Src)
+
+	style := opts.annotationStyle
+	if style.BoxColor == nil {
+		style.BoxColor = defaultAnnotationColor
+	}
+	if style.LineWidth <= 0 {
+		style.LineWidth = _DEFAULT_ANNOTATION_LINE_WIDTH
+	}
+	if style.TextColor == nil {
+		style.TextColor = color.White
+	}
+	if style.TextScale <= 0 {
+		style.TextScale = _DEFAULT_ANNOTATION_TEXT_SCALE
+	}
+	scale := func(p image.Point) image.Point {
+		if meta.ImageSize.X > 0 && meta.ImageSize.Y > 0 {
+			p = image.Pt(p.X*bounds.Dx()/meta.ImageSize.X, p.Y*bounds.Dy()/meta.ImageSize.Y)
+		}
+		return p.Add(bounds.Min)
+	}
+	pen, ink := image.NewUniform(style.BoxColor), image.NewUniform(style.TextColor)
+	for _, word := range words {
+		if word.Rect.Empty() {
+			continue
+		}
+		word.Rect = image.Rectangle{scale(word.Rect.Min), scale(word.Rect.Max)}
+		for i := range word.Quad {
+			word.Quad[i] = scale(word.Quad[i])
+		}
+		word.Chars = append([]Char(nil), word.Chars...)
+		for i := range word.Chars {
+			word.Chars[i].Rect = image.Rectangle{scale(word.Chars[i].Rect.Min), scale(word.Chars[i].Rect.Max)}
+		}
+		for i := range word.Quad {
+			drawLine(canvas, word.Quad[i], word.Quad[(i+1)%len(word.Quad)], style.LineWidth, pen)
+		}
+		if style.DrawText != nil {
+			style.DrawText(canvas, word)
+			continue
+		}
+		at := word.Rect.Min.Sub(image.Pt(0, labelSize(word.Text, style.TextScale).Y))
+		if at.Y < bounds.Min.Y {
+			at.Y = word.Rect.Max.Y
+		}
+		drawLabel(canvas, word.Text, at, style.TextScale, ink, pen)
+	}
+	return png.Encode(w, canvas)
+}
+
+// drawLine draws a line from a to b with a square pen of the width.
+func drawLine(dst draw.Image, a, b image.Point, width int, pen image.Image) {
+	dx, dy := abs(b.X-a.X), -abs(b.Y-a.Y)
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+	offset := image.Pt(width/2, width/2)
+	e := dx + dy
+	for {
+		dot := image.Rectangle{a.Sub(offset), a.Sub(offset).Add(image.Pt(width, width))}
+		draw.Draw(dst, dot, pen, image.Point{}, draw.Over)
+		if a == b {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			a.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			a.Y += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+package baiduocr_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseAndAnnotate(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{
+			{Text: "boxed", Rect: image.Rect(10, 10, 60, 30)},
+			{Text: "unboxed"},
+		}
+	})
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(src, src.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+
+	var buffer bytes.Buffer
+	results, err := ocr.ParseAndAnnotate(encodePNG(t, src), &buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[boxed unboxed]" {
+		t.Errorf("got %s", got)
+	}
+	annotated, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(annotated.At(30, 10)); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("outline is %v, want red", got)
+	}
+	if got := gray(annotated, 30, 20); got != 255 {
+		t.Errorf("inside of the box is %d, want white", got)
+	}
+	// the label above the box, with the stem of the b at its left
+	if got := color.RGBAModel.Convert(annotated.At(10, 0)); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("background of the label is %v, want red", got)
+	}
+	for y := 1; y <= 7; y++ {
+		if got := gray(annotated, 11, y); got != 255 {
+			t.Errorf("stem of the b at (11,%d) is %d, want white", y, got)
+		}
+	}
+	if got := gray(annotated, 12, 1); got == 255 {
+		t.Errorf("pixel right of the top of the b is white, want the background")
+	}
+
+	var drawn []string
+	style := baiduocr.AnnotationStyle{
+		BoxColor:  color.Black,
+		LineWidth: 5,
+		DrawText: func(dst draw.Image, word baiduocr.Word) {
+			drawn = append(drawn, fmt.Sprintf("%s %v", word.Text, word.Rect))
+		},
+	}
+	buffer.Reset()
+	if _, err = ocr.ParseAndAnnotate(encodePNG(t, src), &buffer, baiduocr.SetAnnotationStyle(style)); err != nil {
+		t.Fatal(err)
+	}
+	if annotated, err = png.Decode(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if got := gray(annotated, 30, 12); got != 0 {
+		t.Errorf("wide outline is %d, want black", got)
+	}
+	if got := fmt.Sprint(drawn); got != "[boxed (10,10)-(60,30)]" {
+		t.Errorf("drawn %s", got)
+	}
+	if got := gray(annotated, 10, 0); got != 255 {
+		t.Errorf("label drawn at %d although DrawText is set", got)
+	}
+}
@@ -1,34 +1,198 @@
-// Read Chinese, Japanese and English text from JPEG/PNG image with Baidu OCR services.
-// PNG image will be converted to JPEG on the fly because Baidu OCR recognizes only JPEG image files.
+// Read Chinese, Japanese and English text from JPEG/PNG/GIF image with Baidu OCR services.
+// PNG and GIF images will be converted to JPEG on the fly because Baidu OCR recognizes only JPEG image files.
 package baiduocr
 
 import (
 	"bytes"
-	"encoding/base64"
-	"encoding/json"
+	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/jpeg"
-	"image/png"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/caiguanhao/baiduocr/internal/jpeg444"
 )
 
 type (
 	OCR struct {
 		// Set API key
 		APIKey string
-		// Set API entrypoint path, default is http://apis.baidu.com/apistore/idlocr/ocr
+		// Set API entrypoint path, default is http://apis.baidu.com/apistore/idlocr/ocr.
+		// Gateways answering in the shape of the AIP endpoints (words_result) are supported too.
 		APIPath string
 		// Set request timeout in milliseconds (ms), default is 5000, set to -1 means no timeout
 		TimeoutInMilliseconds int64
+		// Set what the timeout limits, default is TimeoutTotal, the whole request
+		TimeoutMode TimeoutMode
+		// Set a function to receive an audit record of every request before it is sent
+		AuditFunc func(AuditRecord)
+		// Set HTTP client to send requests with, default is a new client for each request
+		HTTPClient *http.Client
+		// Set signer of every request, such as a BCESigner for the signed BCE API, default is nil
+		Signer Signer
+		// Set number of times a request is retried after a network or server error or a maintenance page, default is 0
+		MaxRetries int
+		// Set delay before the first retry, doubled for each further retry, default is 500ms
+		RetryBackoff time.Duration
+		// Set budget of retries shared with other clients, default is nil, meaning retries are only limited by MaxRetries
+		RetryBudget *RetryBudget
+		// Set maximum duration of a call including all retries and backoffs, default is no limit.
+		// TimeoutInMilliseconds still limits each attempt.
+		MaxTotalDuration time.Duration
+		// Set maximum duration of a call from start to end, including decoding, preprocessing and
+		// encoding the image besides the requests, default is no limit. Preprocessing stops between
+		// its steps once it is exceeded. TimeoutInMilliseconds and MaxTotalDuration still limit
+		// the requests within it.
+		OperationTimeout time.Duration
+		// Set maximum number of goroutines preprocessing an image, such as resizing it or applying
+		// SetMedianFilter and SetColorFilter, default is GOMAXPROCS. Each step splits the image into
+		// bands of rows processed concurrently, so lower values save cores for the rest of a program
+		// at the cost of the latency of large images; 1 preprocesses in the calling goroutine.
+		PreprocessParallelism int
+		// Set cache of results, default is no cache
+		Cache ResultCache
+		// Set how long cached results are served without calling Baidu OCR, default is 0,
+		// meaning the cache is only used by ServeStaleOnError
+		CacheTTL time.Duration
+		// Set background color of transparent PNG and GIF images, used unless
+		// overridden by SetPNGBackgroundColor, default is nil, meaning black
+		DefaultPNGBackground color.Color
+		// Set language type used unless overridden by a SetLanguageTypeTo* option, such as
+		// LanguageEnglish, default is empty, meaning LanguageChinese
+		DefaultLanguage string
+		// Set options applied to every call before the options of the call
+		DefaultOptions []BaiduOCROption
+		// Set a function to log warnings with, such as log.Printf, of large request bodies and of PNG
+		// images with ICC profiles other than sRGB, default is nil, meaning nothing is logged
+		LogFunc func(format string, v ...interface{})
+		// Set size in bytes of a request body above which a warning is logged with LogFunc, without
+		// failing the request, default is 0, meaning no warning
+		PayloadWarnBytes int64
+		// Set maximum number of requests in flight at once, further requests wait for one to finish
+		// or for the context of SetContext to be done, default is 0, meaning no limit. OCR values
+		// with the same APIKey, APIPath and MaxConcurrency, such as copies of the same OCR, share
+		// the limit.
+		MaxConcurrency int
+	}
+
+	// AuditRecord describes a request sent to Baidu OCR. It never contains
+	// the API key, and the base64 image field is replaced by its length.
+	AuditRecord struct {
+		Endpoint  string
+		Params    url.Values
+		RequestID string
+		// Hex SHA-256 of the submitted image, empty unless ComputeImageHash
+		// is used
+		ImageSHA256 string
+	}
+
+	// Word is a piece of text recognized by Baidu OCR.
+	Word struct {
+		Text string
+		// Bounding box of the text in the submitted image, empty if the endpoint does not locate text
+		Rect image.Rectangle
+		// Rect relative to the size of the submitted image (ResultMeta.ImageSize), zero if Rect
+		// is empty or the size is unknown
+		NormalizedRect NormalizedRect
+		// Corners of the text clockwise from its top left corner, which is
+		// not the top left one of Rect if the text is rotated. They are the
+		// vertexes Baidu reports if the endpoint locates text with polygons
+		// (general and accurate), or else the corners of Rect.
+		Quad [4]image.Point
+		// Average probability (0 to 1) of the characters of the text, if
+		// HasConfidence; reported by AIP endpoints with RequestConfidence
+		Confidence    float64
+		HasConfidence bool
+		// Script of the text, see DetectScript
+		Script string
+		// Characters of the text with their positions, reported by the AIP
+		// endpoints locating text (general and accurate) with RequestChars,
+		// empty otherwise, see Tokens
+		Chars []Char
+	}
+
+	// NormalizedRect is a rectangle whose coordinates are fractions of the
+	// width and height of an image, from 0 to 1, to draw boxes over the image
+	// displayed at any size.
+	NormalizedRect struct {
+		Left, Top, Right, Bottom float64
+	}
+
+	// Char is a character of a Word, as segmented by Baidu.
+	Char struct {
+		Text string
+		Rect image.Rectangle
+	}
+
+	// PositionedText is a character or word with its box and the center of
+	// the box, see Word.Positioned.
+	PositionedText struct {
+		Text   string
+		Center image.Point
+		Box    image.Rectangle
+	}
+
+	// ResultMeta holds information about a recognition besides the text.
+	ResultMeta struct {
+		// Language Baidu reports having recognized. Only the AIP endpoints
+		// accepting detect_language (general_basic, general, accurate_basic
+		// and accurate) report it, and only when the DetectLanguage option is
+		// used. It is empty otherwise, and always empty for OCR, whose
+		// apistore endpoint has no such field.
+		DetectedLanguage string
+		// Whether the words come from OCR.Cache instead of Baidu OCR
+		Cached bool
+		// Whether the words are a stale cache entry served because the call
+		// to Baidu OCR failed with StaleCause, see ServeStaleOnError
+		Stale      bool
+		StaleCause error
+		// Size of the submitted image, zero if it could not be determined
+		ImageSize image.Point
+		// Endpoint which served the result, set by AipOCR
+		Endpoint string
+		// Groups of indexes into the words, as laid out by Baidu, see
+		// GroupByBaiduLines. Nil if the endpoint did not group the words.
+		Lines [][]int
+		// Headers of the response, such as the remaining quota and request
+		// IDs of Baidu. They are not redacted, as responses carry no
+		// credentials. Nil if the words come from OCR.Cache.
+		Headers http.Header
+		// Time spent in each phase of the call, nil unless ProfileTiming is
+		// used
+		Timing *Timing
+		// Whether results were cut to fit SetMaxTotalChars
+		Truncated bool
+		// Number of the fallback of SetFallbackPreprocessing which gave the
+		// words, from 1, zero if they come from the image as preprocessed by
+		// the other options
+		FallbackPreprocessing int
+		// Number of words in the response of Baidu, before the result
+		// options drop or join any of them, such as SetMinBoxArea or
+		// SingleLineMode, to compare with the number of words returned
+		RawWordCount int
+		// Name of the ICC profile of a PNG image, from its iCCP chunk, empty
+		// if it has none. The decoders ignore profiles, so the colors of an
+		// image with a profile other than sRGB, such as Display P3, are read
+		// as sRGB, shifting them for preprocessing options such as
+		// SetColorFilter; such images are warned about with OCR.LogFunc.
+		ICCProfile string
+		// Granularity of the recognition of AipOCR endpoints locating text
+		// (general and accurate): "big" for words, "small" for characters,
+		// see RequestChars and AutoGranularity. Empty for other endpoints.
+		Granularity string
+		// Hex SHA-256 of the submitted image, empty unless ComputeImageHash
+		// is used
+		ImageSHA256 string
 	}
 
 	BaiduOCROption struct {
@@ -37,26 +201,157 @@ type (
 
 	baiduOCROption struct {
 		languageType string
+		version      string
+		imageType    string
 
 		pngBackgroundColor color.Color
+
+		noChromaSubsampling bool
+
+		blankEntropyThreshold float64
+
+		transforms []transform
+
+		cleanResults bool
+		minBoxArea   int
+		minResults   int
+
+		joinDigits bool
+
+		frameIndex int
+
+		useAlphaChannel bool
+
+		context context.Context
+
+		detectLanguage bool
+
+		serveStale bool
+
+		urlSafeBase64 bool
+
+		baiduLines bool
+
+		requestID string
+
+		confidence bool
+
+		chars bool
+
+		accept         string
+		acceptLanguage string
+
+		stableOrder bool
+
+		detectScript bool
+
+		apiKeyHeader string
+		apiKeyPrefix string
+
+		layoutSpaceWidth float64
+		layoutLineHeight float64
+
+		singleLine bool
+
+		corrections []func(string) string
+
+		autoCleanNoise bool
+
+		maxPixels int
+
+		clientIP func() string
+
+		errorFormatter func(OCRError) string
+
+		trimMode TrimMode
+
+		rejectInvalidUTF8 bool
+
+		formFieldOrder []string
+
+		timing *timing
+
+		smartFormat bool
+
+		jpegEncoder func(io.Writer, image.Image, int) error
+
+		maxTotalChars int
+
+		sortByConfidence bool
+		maxResults       int
+
+		caseMode CaseMode
+
+		fallbackPreprocessing []PreprocessFunc
+
+		targetDPI float64
+
+		expectedContentType string
+		strictContentType   bool
+
+		unredactedDump bool
+
+		translateErrors bool
+
+		clientVersion string
+
+		autoGranularity bool
+
+		annotationStyle AnnotationStyle
+
+		preprocessParallelism int
+
+		computeImageHash bool
 	}
 
 	baiduOCRRet struct {
-		ErrMsg  string `json:"errMsg"`
-		RetData []struct {
-			Rect struct {
-				Height string `json:"height"`
-				Left   string `json:"left"`
-				Top    string `json:"top"`
-				Width  string `json:"width"`
-			} `json:"rect"`
-			Word string `json:"word"`
-		} `json:"retData"`
+		ErrNum  int            `json:"errNum"`
+		ErrMsg  string         `json:"errMsg"`
+		RetData []baiduOCRWord `json:"retData"`
+	}
+
+	baiduOCRWord struct {
+		Rect struct {
+			Height string `json:"height"`
+			Left   string `json:"left"`
+			Top    string `json:"top"`
+			Width  string `json:"width"`
+		} `json:"rect"`
+		Word string `json:"word"`
 	}
 )
 
+// ErrBlankImage is returned with no results when SkipBlankImages is used and
+// the image is considered blank. Baidu OCR is not called in that case.
+var ErrBlankImage = errors.New("image is blank, skipped")
+
+// ErrUnsupportedFormat is returned when the image is not JPEG, PNG or GIF, or
+// WebP with the webp build tag. The returned error wraps it with the
+// detected content type, test for it with errors.Is.
+var ErrUnsupportedFormat = errors.New("unrecognized image file format")
+
+// ErrNoText is matched by the OCRError returned when Baidu OCR recognizes
+// no text in the image, or no result remains after the result options are
+// applied, test for it with errors.Is.
+var ErrNoText = errors.New("BaiduOCR failed to recognize any text in the image.")
+
 const (
-	_DEFAULT_LANG = "CHN_ENG"
+	_API_PATH = "http://apis.baidu.com/apistore/idlocr/ocr"
+
+	_DEFAULT_LANG    = "CHN_ENG"
+	_DEFAULT_VERSION = "v1"
+
+	// errNum of the apistore gateway errors start from this
+	_APISTORE_ERRORS = 300000
+
+	// quality of the JPEG encoding of converted images
+	_JPEG_QUALITY = 100
+
+	_DEFAULT_ACCEPT         = "application/json"
+	_DEFAULT_API_KEY_HEADER = "apikey"
+
+	_IMAGE_TYPE_BASE64 = "1"
+	_IMAGE_TYPE_URL    = "2"
 
 	_CHINESE  = "CHN_ENG"
 	_ENGLISH  = "ENG"
@@ -78,7 +373,112 @@ func SetLanguageTypeToJapanese() BaiduOCROption {
 	return BaiduOCROption{func(option *baiduOCROption) { option.languageType = _JAPANESE }}
 }
 
-// If the image is a PNG with transparent background, use this option to set the background color.
+// Option to set the version parameter of the request, default is v1.
+func SetVersion(v string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.version = v }}
+}
+
+// Version of this package, sent in the User-Agent header of the requests as
+// baiduocr/Version, see SetClientVersion.
+const Version = "1.0.0"
+
+// Option to identify the client in the User-Agent header of the requests,
+// such as myapp/2.1, followed by the version of this package: "myapp/2.1
+// baiduocr/1.0.0". Baidu and gateways may log it, such as for their support
+// to tell the requests of the client; no form field is sent, as the endpoints
+// define none for it. The default is baiduocr/Version alone.
+func SetClientVersion(v string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.clientVersion = v }}
+}
+
+// Values of the imagetype parameter, see SetImageType.
+const (
+	// The image field is the base64 encoding of the image bytes
+	ImageTypeBase64 = _IMAGE_TYPE_BASE64
+	// The image field is the URL of the image, see ParseImageURL
+	ImageTypeURL = _IMAGE_TYPE_URL
+)
+
+// Option to set the imagetype parameter of the request, ImageTypeBase64 or
+// ImageTypeURL. The default is ImageTypeBase64 for methods submitting image
+// bytes, which cannot be used with any other image type, and ParseImageURL
+// always uses ImageTypeURL. It has no effect on AipOCR.
+func SetImageType(t string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.imageType = t }}
+}
+
+// Option to ask the AIP endpoints to detect the language of the text and
+// report it in ResultMeta.DetectedLanguage. It has no effect on OCR.
+func DetectLanguage() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.detectLanguage = true }}
+}
+
+// Option to return the last cached result of the image when the call to
+// Baidu OCR fails, however old it is, instead of the error. The result meta
+// of ParseDetailed then has Stale set and the error in StaleCause, so that
+// callers can tell users the text may be out of date. Without a cache entry
+// the error is returned as usual. It has no effect unless OCR.Cache is set.
+func ServeStaleOnError() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.serveStale = true }}
+}
+
+// Option to encode the image field with the URL-safe base64 alphabet (- and _
+// instead of + and /). Both the apistore endpoint used by OCR and the AIP
+// endpoints expect standard base64, which is the default, and fail to
+// recognize anything when sent the other alphabet. Use it only for gateways
+// documented to require URL-safe base64.
+func SetURLSafeBase64() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.urlSafeBase64 = true }}
+}
+
+// Option to send the form fields of the request in the given order, fields
+// not listed following in sorted order. The image field goes where it is
+// listed, or last if it is not. By default all fields but the image are
+// sorted by name, as url.Values.Encode does, and the image is last. Neither
+// the apistore endpoint used by OCR nor the AIP endpoints care about the
+// order; use it only for gateways or proxies in front of them that validate
+// the order of fields, such as ones checking a signature of the body.
+func SetFormFieldOrder(fields ...string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.formFieldOrder = fields }}
+}
+
+// Option to make ParseLines group words the way Baidu laid them out instead
+// of by their positions. The AIP endpoints accepting the paragraph parameter
+// (general_basic, general, accurate_basic and accurate) are asked to return
+// their paragraphs_result, each group of which becomes a line. The apistore
+// endpoint used by OCR never groups words, so the grouping falls back to
+// positions there.
+func GroupByBaiduLines() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.baiduLines = true }}
+}
+
+// Option to set an ID for the request, for tracing it across systems. The ID
+// is sent in the X-Request-Id header, passed to OCR.AuditFunc in the audit
+// record and appended to the error message if the request fails. Without
+// this option, a random UUID is generated for the request if OCR.AuditFunc
+// is set, and no ID is used otherwise.
+func SetRequestID(id string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.requestID = id }}
+}
+
+// Option to ask the AIP endpoints for the confidence of each recognized word,
+// see Word.Confidence. It is sent as the probability parameter, which
+// general_basic, general, accurate_basic, accurate and handwriting accept.
+// It has no effect on OCR, whose apistore endpoint reports no confidence.
+func RequestConfidence() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.confidence = true }}
+}
+
+// Option to ask the AIP endpoints for the characters of each recognized word,
+// see Word.Chars. It is sent as recognize_granularity=small, which only
+// general and accurate accept. It has no effect on other endpoints and on
+// OCR, whose apistore endpoint never segments words.
+func RequestChars() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.chars = true }}
+}
+
+// If the image is a PNG or GIF with transparent background, use this option to set the background color.
+// It overrides OCR.DefaultPNGBackground, transparent background becomes black if neither is set.
 func SetPNGBackgroundColor(bgColor color.Color) BaiduOCROption {
 	return BaiduOCROption{func(option *baiduOCROption) { option.pngBackgroundColor = bgColor }}
 }
@@ -88,108 +488,373 @@ func SetPNGBackgroundColorRGBA(r, g, b, a uint8) BaiduOCROption {
 	return BaiduOCROption{func(option *baiduOCROption) { option.pngBackgroundColor = color.RGBA{r, g, b, a} }}
 }
 
+// Option to encode converted images without chroma subsampling (4:4:4).
+// By default the JPEG encoder keeps full brightness resolution but halves
+// the color resolution in both directions (4:2:0), which can blur the edges
+// of colored text. Encoding 4:4:4 keeps them sharp at the cost of a larger
+// payload: the PNG fixtures in test/fixtures grow by 30% to 50%. On the
+// colored captchas of test/fixtures/simple-captcha, the largest error of a
+// decoded pixel drops from about 90 to 4 (of 255), and the mean error from
+// 1.8 to 0.1; on black and white images both encodings are near lossless.
+// It has no effect on JPEG input, which is submitted as is.
+func SetNoChromaSubsampling() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.noChromaSubsampling = true }}
+}
+
+// Option to encode converted images with encode instead of the standard
+// image/jpeg encoder, such as a binding of mozjpeg producing smaller files.
+// It is called with the quality the standard encoder would use, 100, and
+// must write a JPEG image to w: an error is returned if what it writes is not
+// recognized as JPEG. It takes precedence over SetNoChromaSubsampling. It has
+// no effect on JPEG input, which is submitted as is.
+func SetJPEGEncoder(encode func(w io.Writer, img image.Image, quality int) error) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.jpegEncoder = encode }}
+}
+
+// Option to skip images that are blank, such as empty scanned pages, without
+// calling Baidu OCR. The image is converted to grayscale and the Shannon
+// entropy of its histogram is computed, which is 0 for a solid color and up
+// to 8 (bits) for an image using every gray level equally. If the entropy is
+// below entropyThreshold, ErrBlankImage is returned. A threshold around 0.5
+// skips solid and nearly solid pages while keeping pages with a few words.
+func SkipBlankImages(entropyThreshold float64) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.blankEntropyThreshold = entropyThreshold }}
+}
+
+// Option to set the context of the request, so that it can be cancelled or
+// given a deadline in addition to TimeoutInMilliseconds.
+func SetContext(ctx context.Context) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.context = ctx }}
+}
+
+// Option to set the Accept header of the requests, default is
+// application/json. Baidu answers JSON whatever the header, some gateways in
+// front of it may not. Set to empty to send no Accept header.
+func SetAccept(mediaType string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.accept = mediaType }}
+}
+
+// Option to set the Accept-Language header of the requests, such as zh-CN,
+// to hint gateways the language of the expected response. The default is to
+// send no Accept-Language header. Baidu ignores it, use the language type
+// options to set the language of the text, and TranslateErrorMessages for
+// errors in English.
+func SetAcceptLanguage(lang string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.acceptLanguage = lang }}
+}
+
+// Option to send the API key in the header name, after prefix, instead of in
+// the apikey header expected by the apistore endpoint, for gateways expecting
+// another scheme. For example, SetAPIKeyHeader("Authorization", "Bearer ")
+// sends "Authorization: Bearer <API key>". It has no effect on AipOCR, which
+// sends an access token in the URL.
+func SetAPIKeyHeader(name, prefix string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.apiKeyHeader, option.apiKeyPrefix = name, prefix }}
+}
+
 func (ocr OCR) ParseImage(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
-	switch http.DetectContentType(imageBytes) {
-	case "image/png":
-		results, err = ocr.ParsePNG(imageBytes, options...)
-	case "image/jpeg":
-		results, err = ocr.ParseJPEG(imageBytes, options...)
-	default:
-		err = errors.New("unrecognized image file format")
-	}
+	var words []Word
+	words, _, err = ocr.ParseDetailed(imageBytes, options...)
+	results = wordTexts(words)
 	return
 }
 
+// Same as ParseImage. The format is detected from the bytes, so PNG and GIF
+// images are converted as well.
 func (ocr OCR) ParseJPEG(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
-	opts := baiduOCROption{
-		languageType: _DEFAULT_LANG,
+	return ocr.ParseImage(imageBytes, options...)
+}
+
+// Same as ParseImage. The format is detected from the bytes, so JPEG and GIF
+// images are accepted as well.
+func (ocr OCR) ParsePNG(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
+	return ocr.ParseImage(imageBytes, options...)
+}
+
+// Read text from JPEG/PNG image, returning each recognized word with its
+// position and information about the recognition.
+func (ocr OCR) ParseDetailed(imageBytes []byte, options ...BaiduOCROption) (words []Word, meta ResultMeta, err error) {
+	opts, timedOut, cancel := ocr.newOptions(options).withOperationTimeout(ocr.OperationTimeout)
+	defer cancel()
+	defer func() { err = timedOut(err) }()
+	warnICCProfile(ocr.LogFunc, imageBytes)
+	words, meta, err = opts.withFallbackPreprocessing(func(opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+		var jpegBytes []byte
+		jpegBytes, err = toJPEG(imageBytes, opts)
+		if err != nil {
+			return
+		}
+		return ocr.parseJPEG(jpegBytes, opts)
+	})
+	meta.Timing = opts.timingResult()
+	meta.ICCProfile, _ = pngICCProfile(imageBytes)
+	return
+}
+
+func (ocr OCR) parseJPEG(imageBytes []byte, opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+	err = opts.checkImageRequest()
+	if err != nil {
+		return
 	}
-	for _, option := range options {
-		option.f(&opts)
+	err = checkBlank(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	words, meta, err = ocr.cachedRequest(imageBytes, opts)
+	if err != nil {
+		return
 	}
+	meta.ImageSHA256 = opts.imageHash(imageBytes)
+	words, err = opts.postprocess(words, &meta)
+	return
+}
 
-	reqBody := strings.NewReader(url.Values{
-		"fromdevice":   {"pc"},
-		"clientip":     {"10.10.10.0"},
-		"detecttype":   {"LocateRecognize"},
-		"languagetype": {opts.languageType},
-		"imagetype":    {"1"},
-		"image":        {base64.StdEncoding.EncodeToString(imageBytes)},
-		"version":      {"v1"},
-		"sizetype":     {"small"},
-	}.Encode())
+func (ocr OCR) cachedRequest(imageBytes []byte, opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+	if ocr.Cache == nil {
+		words, meta, err = ocr.requestWithRetries(imageBytes, opts)
+		return
+	}
 
 	path := ocr.APIPath
 	if len(path) == 0 {
-		path = "http://apis.baidu.com/apistore/idlocr/ocr"
+		path = _API_PATH
+	}
+	key := cacheKey(path, imageBytes, opts)
+	cached, storedAt, found := ocr.Cache.Get(key)
+	if found && currentClock.now().Sub(storedAt) < ocr.CacheTTL {
+		words, meta.Cached, meta.ImageSize = cached, true, imageSize(imageBytes)
+		return
+	}
+	words, meta, err = ocr.requestWithRetries(imageBytes, opts)
+	if err == nil {
+		ocr.Cache.Set(key, words, currentClock.now())
+	} else if found && opts.serveStale {
+		words, meta = cached, ResultMeta{Cached: true, Stale: true, StaleCause: err, ImageSize: imageSize(imageBytes)}
+		err = nil
 	}
+	return
+}
 
-	var req *http.Request
-	req, err = http.NewRequest("POST", path, reqBody)
+func (ocr OCR) request(imageBytes []byte, opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+	meta.ImageSize = imageSize(imageBytes)
+	var release func()
+	release, err = ocr.acquireSlot(opts.context)
 	if err != nil {
 		return
 	}
-	req.Header.Set("content-type", "application/x-www-form-urlencoded")
-	req.Header.Set("apikey", ocr.APIKey)
+	defer release()
 
-	var timeout time.Duration
-	ms := ocr.TimeoutInMilliseconds
-	if ms < -1 {
-		panic("TimeoutInMilliseconds must not be less than -1")
-	} else if ms > -1 {
-		if ms == 0 {
-			ms = 5000
-		}
-		timeout = time.Duration(ms) * time.Millisecond
-	}
-	client := &http.Client{
-		Timeout: timeout,
+	var req *http.Request
+	var requestID string
+	req, requestID, err = ocr.newRequest(imageBytes, opts)
+	if requestID != "" {
+		defer func() {
+			if err != nil {
+				err = fmt.Errorf("%w (request ID %s)", err, requestID)
+			}
+		}()
 	}
-	var resp *http.Response
-	resp, err = client.Do(req)
 	if err != nil {
 		return
 	}
 
-	defer resp.Body.Close()
 	var body []byte
-	body, err = ioutil.ReadAll(resp.Body)
+	done := opts.track(networkPhase)
+	body, meta.Headers, err = doRequest(ocr.HTTPClient, ocr.TimeoutInMilliseconds, ocr.TimeoutMode, ocr.Signer, req)
+	done()
+	if err != nil {
+		return
+	}
+	body, err = opts.decodeUTF8(body)
 	if err != nil {
 		return
 	}
 
-	var ret baiduOCRRet
-	err = json.Unmarshal(body, &ret)
+	words, err = parseResponse(body, &meta)
+	err = opts.formatted(err)
+	return
+}
+
+// checkImageRequest returns an error if the options cannot submit image
+// bytes to the apistore endpoint.
+func (opts baiduOCROption) checkImageRequest() error {
+	if opts.version == "" {
+		return errors.New("version must not be empty")
+	}
+	switch opts.imageType {
+	case ImageTypeBase64:
+	case ImageTypeURL:
+		return errors.New("image type of URL cannot submit image bytes, use ParseImageURL")
+	default:
+		return fmt.Errorf("unknown image type %q", opts.imageType)
+	}
+	return nil
+}
+
+// detectType returns the detecttype parameter of the apistore endpoint.
+func (opts baiduOCROption) detectType() string {
+	if opts.singleLine {
+		return "Recognize"
+	}
+	return "LocateRecognize"
+}
+
+// newRequest returns the request submitting the image, and its ID if it has
+// one. The request is passed to AuditFunc.
+func (ocr OCR) newRequest(imageBytes []byte, opts baiduOCROption) (req *http.Request, requestID string, err error) {
+	params := url.Values{
+		"fromdevice":   {"pc"},
+		"clientip":     {opts.nextClientIP()},
+		"detecttype":   {opts.detectType()},
+		"languagetype": {opts.languageType},
+		"imagetype":    {opts.imageType},
+		"version":      {opts.version},
+		"sizetype":     {"small"},
+	}
+	form := opts.newImageForm(params, imageBytes)
+
+	path := ocr.APIPath
+	if len(path) == 0 {
+		path = _API_PATH
+	}
+
+	requestID = opts.requestID
+	if requestID == "" && ocr.AuditFunc != nil {
+		requestID = newRequestID()
+	}
+
+	if ocr.AuditFunc != nil {
+		record := newAuditRecord(path, form, opts)
+		record.RequestID = requestID
+		ocr.AuditFunc(record)
+	}
+
+	req, err = form.newRequest(path)
 	if err != nil {
 		return
 	}
+	warnLargePayload(ocr.LogFunc, ocr.PayloadWarnBytes, path, req.ContentLength)
+	if opts.context != nil {
+		req = req.WithContext(opts.context)
+	}
+	opts.setHeaders(req)
+	req.Header.Set(opts.apiKeyHeader, opts.apiKeyPrefix+ocr.APIKey)
+	if requestID != "" {
+		req.Header.Set("X-Request-Id", requestID)
+	}
+	return
+}
 
-	if len(ret.RetData) == 0 {
-		msg := "BaiduOCR failed to recognize any text in the image."
-		if ret.ErrMsg != "" {
-			msg += fmt.Sprintf(" reason: %s", ret.ErrMsg)
-		}
-		err = errors.New(msg)
+// Read text from JPEG/PNG image and group the words into lines, each line
+// ordered from left to right, lines ordered from top to bottom. See
+// GroupByBaiduLines for using the grouping of Baidu instead.
+func (ocr OCR) ParseLines(imageBytes []byte, options ...BaiduOCROption) (lines [][]string, err error) {
+	var words []Word
+	var meta ResultMeta
+	words, meta, err = ocr.ParseDetailed(imageBytes, options...)
+	if err != nil {
 		return
 	}
-	for _, data := range ret.RetData {
-		results = append(results, data.Word)
+	lines = lineTexts(words, meta, ocr.newOptions(options))
+	return
+}
+
+// Read only the digit sequences from JPEG/PNG image, such as the answer of a
+// numeric captcha, ignoring any letters or symbols Baidu recognizes around
+// them. Full-width digits (０ to ９) are converted to ASCII. Each run of
+// consecutive digits is a result, unless JoinDigits is used. Results with no
+// digit at all fail as if no text was recognized.
+func (ocr OCR) ParseDigits(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
+	var texts []string
+	texts, err = ocr.ParseImage(imageBytes, options...)
+	if err != nil {
+		return
+	}
+	opts := ocr.newOptions(options)
+	results = extractDigits(texts, opts.joinDigits)
+	if len(results) == 0 {
+		err = opts.formatted(noTextError("no digits"))
 	}
 	return
 }
 
-func (ocr OCR) ParsePNG(imageBytes []byte, options ...BaiduOCROption) (results []string, err error) {
-	opts := baiduOCROption{}
-	for _, option := range options {
-		option.f(&opts)
+// Read the answer of a captcha from JPEG/PNG image: the results Baidu splits
+// it into are joined without a separator, from left to right by their
+// bounding boxes, giving "3560" instead of "35" and "60". It is ParseImage
+// with SingleLineMode, see there for how the image is recognized.
+func (ocr OCR) ParseCaptcha(imageBytes []byte, options ...BaiduOCROption) (answer string, err error) {
+	var words []Word
+	words, _, err = ocr.ParseDetailed(imageBytes, append(options[:len(options):len(options)], SingleLineMode())...)
+	answer = strings.Join(wordTexts(words), "")
+	return
+}
+
+// Read text from an image made of rows×cols evenly-spaced cells, such as a
+// sprite packing several captchas. Each cell is cropped and recognized on its
+// own, results are returned in row-major order. If the image size is not
+// divisible by rows or cols, the remainder pixels are spread over the cells so
+// that their sizes differ by at most one pixel. Cells where Baidu finds no
+// text, such as empty slots of a sprite, or skipped by SkipBlankImages, have
+// empty results. Preprocessing options, such as SetCropRect, apply to the
+// whole image before it is split, so the grid is laid over the preprocessed
+// image.
+func (ocr OCR) ParseGrid(imageBytes []byte, rows, cols int, options ...BaiduOCROption) (results [][]string, err error) {
+	if rows < 1 || cols < 1 {
+		err = errors.New("rows and cols must be at least 1")
+		return
 	}
+	opts, timedOut, cancel := ocr.newOptions(options).withOperationTimeout(ocr.OperationTimeout)
+	defer cancel()
+	defer func() { err = timedOut(err) }()
+	var img image.Image
+	img, err = normalizedImage(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	for i, cell := range gridCells(img.Bounds(), rows, cols) {
+		err = opts.contextErr()
+		if err != nil {
+			return
+		}
+		var jpegBytes []byte
+		done := opts.track(encodePhase)
+		jpegBytes, err = encodeJPEGBytes(crop(img, cell), opts)
+		done()
+		if err != nil {
+			return
+		}
+		var words []Word
+		words, _, err = ocr.parseJPEG(jpegBytes, opts)
+		cellResults := wordTexts(words)
+		if errors.Is(err, ErrNoText) || errors.Is(err, ErrBlankImage) {
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("cell at row %d, column %d: %w", i/cols, i%cols, err)
+			return
+		}
+		results = append(results, cellResults)
+	}
+	return
+}
 
-	var buffer *bytes.Buffer
-	buffer, err = pngTojpeg(bytes.NewReader(imageBytes), opts.pngBackgroundColor)
+// Read text from the image at imageURL, which Baidu OCR downloads itself.
+// The image is submitted with ImageTypeURL, so options converting or
+// examining the image, such as SetPNGBackgroundColor or SkipBlankImages, have
+// no effect. OCR.Cache is not used.
+func (ocr OCR) ParseImageURL(imageURL string, options ...BaiduOCROption) (results []string, err error) {
+	opts := ocr.newOptions(append(options[:len(options):len(options)], SetImageType(ImageTypeURL)))
+	if opts.version == "" {
+		err = errors.New("version must not be empty")
+		return
+	}
+	var words []Word
+	var meta ResultMeta
+	words, meta, err = ocr.requestWithRetries([]byte(imageURL), opts)
 	if err != nil {
 		return
 	}
-	results, err = ocr.ParseJPEG((*buffer).Bytes(), options...)
+	words, err = opts.postprocess(words, &meta)
+	results = wordTexts(words)
 	return
 }
 
@@ -217,7 +882,7 @@ func (ocr OCR) ParseJPEGFile(filename string, options ...BaiduOCROption) (result
 
 // Read text from PNG image file. PNG image will be converted to JPEG image on the fly.
 // By default, transparent background of PNG image will become black.
-// You can add an option to specify the background color for better OCR results.
+// You can set OCR.DefaultPNGBackground or add an option to specify the background color for better OCR results.
 func (ocr OCR) ParsePNGFile(filename string, options ...BaiduOCROption) (results []string, err error) {
 	var file []byte
 	file, err = ioutil.ReadFile(filename)
@@ -228,20 +893,423 @@ func (ocr OCR) ParsePNGFile(filename string, options ...BaiduOCROption) (results
 	return
 }
 
-func pngTojpeg(reader io.Reader, pngBackgroundColor color.Color) (buffer *bytes.Buffer, err error) {
-	var img image.Image
-	img, err = png.Decode(reader)
+// Same as ParseImageFile, with the results joined by sep into one string.
+func (ocr OCR) ParseImageFileString(filename, sep string, options ...BaiduOCROption) (result string, err error) {
+	var results []string
+	results, err = ocr.ParseImageFile(filename, options...)
+	result = strings.Join(results, sep)
+	return
+}
+
+// Same as ParseJPEGFile, with the results joined by sep into one string.
+func (ocr OCR) ParseJPEGFileString(filename, sep string, options ...BaiduOCROption) (result string, err error) {
+	var results []string
+	results, err = ocr.ParseJPEGFile(filename, options...)
+	result = strings.Join(results, sep)
+	return
+}
+
+// Same as ParsePNGFile, with the results joined by sep into one string.
+func (ocr OCR) ParsePNGFileString(filename, sep string, options ...BaiduOCROption) (result string, err error) {
+	var results []string
+	results, err = ocr.ParsePNGFile(filename, options...)
+	result = strings.Join(results, sep)
+	return
+}
+
+func newOptions(options []BaiduOCROption) baiduOCROption {
+	opts := baiduOCROption{
+		languageType: _DEFAULT_LANG,
+		version:      _DEFAULT_VERSION,
+		imageType:    ImageTypeBase64,
+		accept:       _DEFAULT_ACCEPT,
+		apiKeyHeader: _DEFAULT_API_KEY_HEADER,
+	}
+	for _, option := range options {
+		option.f(&opts)
+	}
+	return opts
+}
+
+// newOptions is the same as the newOptions function, but defaults the
+// background color to DefaultPNGBackground and the parallelism to
+// PreprocessParallelism, then applies DefaultOptions.
+func (ocr OCR) newOptions(options []BaiduOCROption) baiduOCROption {
+	defaults := []BaiduOCROption{
+		SetPNGBackgroundColor(ocr.DefaultPNGBackground),
+		withPreprocessParallelism(ocr.PreprocessParallelism),
+	}
+	if lang := ocr.DefaultLanguage; lang != "" {
+		defaults = append(defaults, BaiduOCROption{func(option *baiduOCROption) { option.languageType = lang }})
+	}
+	defaults = append(defaults, ocr.DefaultOptions...)
+	return newOptions(append(defaults, options...))
+}
+
+// setHeaders sets the headers of the options on req.
+func (opts baiduOCROption) setHeaders(req *http.Request) {
+	userAgent := "baiduocr/" + Version
+	if opts.clientVersion != "" {
+		userAgent = opts.clientVersion + " " + userAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if opts.accept != "" {
+		req.Header.Set("Accept", opts.accept)
+	}
+	if opts.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", opts.acceptLanguage)
+	}
+}
+
+func wordTexts(words []Word) (texts []string) {
+	for _, word := range words {
+		texts = append(texts, word.Text)
+	}
+	return
+}
+
+// Tokens returns the texts of the characters Baidu segmented the word into,
+// or the whole text of the word as the single token if it did not.
+func (word Word) Tokens() (tokens []string) {
+	if len(word.Chars) == 0 {
+		return []string{word.Text}
+	}
+	for _, char := range word.Chars {
+		tokens = append(tokens, char.Text)
+	}
+	return
+}
+
+// Positioned returns the characters Baidu segmented the word into with their
+// boxes, such as to map them to the fields of a form, or the whole word with
+// its Rect if it did not, as without RequestChars. The center of a box is
+// rounded down to a pixel. A word without a box, as returned by endpoints not
+// locating text, is returned with an empty Box and a zero Center.
+func (word Word) Positioned() (texts []PositionedText) {
+	if len(word.Chars) == 0 {
+		return []PositionedText{positionedText(word.Text, word.Rect)}
+	}
+	for _, char := range word.Chars {
+		texts = append(texts, positionedText(char.Text, char.Rect))
+	}
+	return
+}
+
+// Angle returns the angle of the baseline of the word in degrees from the
+// horizontal, from its bottom left to its bottom right corner in Quad,
+// positive if the text rises to the right, up to 180 for text upside down.
+// It tells text actually rotated, as in captchas, from a slightly off box. It
+// is 0 for words located with axis-aligned boxes only, as Quad is then the
+// corners of Rect, and for words not located at all.
+func (word Word) Angle() float64 {
+	baseline := word.Quad[2].Sub(word.Quad[3])
+	return math.Atan2(float64(-baseline.Y), float64(baseline.X)) * 180 / math.Pi
+}
+
+func positionedText(text string, box image.Rectangle) PositionedText {
+	if box.Empty() {
+		return PositionedText{Text: text}
+	}
+	return PositionedText{text, box.Min.Add(box.Max).Div(2), box}
+}
+
+// Scale returns the rectangle in pixels of an image of the size.
+func (rect NormalizedRect) Scale(size image.Point) image.Rectangle {
+	return image.Rect(
+		int(math.Round(rect.Left*float64(size.X))), int(math.Round(rect.Top*float64(size.Y))),
+		int(math.Round(rect.Right*float64(size.X))), int(math.Round(rect.Bottom*float64(size.Y))),
+	)
+}
+
+// normalizeRect returns rect relative to size.
+func normalizeRect(rect image.Rectangle, size image.Point) NormalizedRect {
+	if rect.Empty() || size.X < 1 || size.Y < 1 {
+		return NormalizedRect{}
+	}
+	w, h := float64(size.X), float64(size.Y)
+	return NormalizedRect{
+		float64(rect.Min.X) / w, float64(rect.Min.Y) / h,
+		float64(rect.Max.X) / w, float64(rect.Max.Y) / h,
+	}
+}
+
+// rectQuad returns the corners of rect clockwise from its top left corner.
+func rectQuad(rect image.Rectangle) [4]image.Point {
+	return [4]image.Point{rect.Min, {rect.Max.X, rect.Min.Y}, rect.Max, {rect.Min.X, rect.Max.Y}}
+}
+
+func requestTimeout(timeoutInMilliseconds int64) (timeout time.Duration) {
+	ms := timeoutInMilliseconds
+	if ms < -1 {
+		panic("TimeoutInMilliseconds must not be less than -1")
+	} else if ms > -1 {
+		if ms == 0 {
+			ms = 5000
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	return
+}
+
+// doRequest sends req with client and returns the response body and headers, after
+// signing it with signer if not nil. If client is nil, a new client is
+// created for the request.
+func doRequest(client *http.Client, timeoutInMilliseconds int64, mode TimeoutMode, signer Signer, req *http.Request) (body []byte, header http.Header, err error) {
+	var reader io.ReadCloser
+	reader, header, err = sendRequest(client, timeoutInMilliseconds, mode, signer, req)
 	if err != nil {
 		return
 	}
-	if pngBackgroundColor != nil {
-		bounds := img.Bounds()
-		newImg := image.NewRGBA(bounds)
-		draw.Draw(newImg, bounds, &image.Uniform{pngBackgroundColor}, image.ZP, draw.Src)
-		draw.Draw(newImg, bounds, img, image.ZP, draw.Over)
-		img = newImg
+	defer reader.Close()
+	body, err = ioutil.ReadAll(reader)
+	return
+}
+
+// sendRequest is the same as doRequest, but returns the response body as it
+// is received. The timeout keeps applying to reading it, until it is closed.
+func sendRequest(client *http.Client, timeoutInMilliseconds int64, mode TimeoutMode, signer Signer, req *http.Request) (body io.ReadCloser, header http.Header, err error) {
+	if signer != nil {
+		err = signer.Sign(req)
+		if err != nil {
+			// close the body as client.Do would, stopping the goroutine writing it
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return
+		}
+	}
+	timeout := requestTimeout(timeoutInMilliseconds)
+	cancel := func() {}
+	var idle *idleTimeout
+	switch {
+	case timeout == 0:
+	case mode == TimeoutIdle:
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(req.Context())
+		idle = newIdleTimeout(timeout, cancel)
+		req = req.WithContext(ctx)
+		if req.Body != nil {
+			req.Body = idleReader{req.Body, idle}
+		}
+	case client == nil:
+		client = &http.Client{
+			Timeout: timeout,
+		}
+	default:
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		req = req.WithContext(ctx)
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	var resp *http.Response
+	resp, err = client.Do(req)
+	if err != nil {
+		redactURLError(err)
+		// the query may carry the access token
+		endpoint := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}
+		err = fmt.Errorf("baiduocr: request to %s failed: %w", endpoint.String(), err)
+	} else if resp.StatusCode >= 500 {
+		resp.Body.Close()
+		err = serverError{resp.Status}
+	} else {
+		resp.Body, err = checkHTML(resp)
+	}
+	if err != nil {
+		err = idle.wrap(err)
+		idle.stop()
+		cancel()
+		return
+	}
+	header = resp.Header
+	body = responseBody{idleReader{resp.Body, idle}, func() {
+		idle.stop()
+		cancel()
+	}}
+	return
+}
+
+// redactedQueryParams are the query parameters of requests carrying
+// credentials, which errors of the requests must not print.
+var redactedQueryParams = []string{"access_token", "client_secret"}
+
+// redactURLError redacts the credentials in the URL of err, if it is a
+// *url.Error.
+func redactURLError(err error) {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return
+	}
+	urlErr.URL = redactURL(urlErr.URL)
+}
+
+// redactURL returns rawURL with the values of redactedQueryParams redacted.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	redacted := false
+	for _, key := range redactedQueryParams {
+		if query.Has(key) {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawURL
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func unsupportedFormatError(contentType string) error {
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, contentType)
+}
+
+// newRequestID returns a random (version 4) UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func newAuditRecord(endpoint string, form imageForm, opts baiduOCROption) AuditRecord {
+	redacted := url.Values{}
+	for key, values := range form.params {
+		redacted[key] = append([]string(nil), values...)
+	}
+	if form.image != nil {
+		redacted.Set("image", fmt.Sprintf("[redacted %d bytes]", form.encodedImageLength()))
 	}
+	return AuditRecord{Endpoint: endpoint, Params: redacted, ImageSHA256: opts.imageHash(form.image)}
+}
+
+// flatten removes the transparency of a PNG or GIF image, by replacing it with
+// its alpha channel if UseAlphaChannel is used, or else by drawing it over
+// the background color if set.
+func (opts baiduOCROption) flatten(img image.Image) image.Image {
+	if opts.useAlphaChannel {
+		return alphaToGray(img)
+	}
+	return flattenPNG(img, opts.pngBackgroundColor)
+}
+
+func flattenPNG(img image.Image, pngBackgroundColor color.Color) image.Image {
+	if pngBackgroundColor == nil {
+		return img
+	}
+	bounds := img.Bounds()
+	newImg := image.NewRGBA(bounds)
+	draw.Draw(newImg, bounds, &image.Uniform{pngBackgroundColor}, image.ZP, draw.Src)
+	draw.Draw(newImg, bounds, img, bounds.Min, draw.Over)
+	return newImg
+}
+
+func encodeJPEG(img image.Image, opts baiduOCROption) (buffer *bytes.Buffer, err error) {
 	buffer = new(bytes.Buffer)
-	err = jpeg.Encode(buffer, img, &jpeg.Options{100})
+	switch {
+	case opts.jpegEncoder != nil:
+		err = opts.jpegEncoder(buffer, img, _JPEG_QUALITY)
+		if contentType := http.DetectContentType(buffer.Bytes()); err == nil && contentType != "image/jpeg" {
+			err = fmt.Errorf("JPEG encoder produced %s instead of JPEG", contentType)
+		}
+	case opts.noChromaSubsampling:
+		err = jpeg444.Encode(buffer, img, &jpeg444.Options{Quality: _JPEG_QUALITY})
+	default:
+		err = jpeg.Encode(buffer, img, &jpeg.Options{Quality: _JPEG_QUALITY})
+	}
+	return
+}
+
+// imageSize returns the size of the submitted image, JPEG or, with
+// SmartFormat, PNG.
+func imageSize(imageBytes []byte) image.Point {
+	config, _, err := image.DecodeConfig(bytes.NewReader(imageBytes))
+	if err != nil {
+		return image.Point{}
+	}
+	return image.Pt(config.Width, config.Height)
+}
+
+func encodeJPEGBytes(img image.Image, opts baiduOCROption) (jpegBytes []byte, err error) {
+	var buffer *bytes.Buffer
+	buffer, err = encodeJPEG(img, opts)
+	if err != nil {
+		return
+	}
+	jpegBytes = buffer.Bytes()
+	return
+}
+
+// checkBlank returns ErrBlankImage if blank images are to be skipped and the
+// submitted image is blank.
+func checkBlank(imageBytes []byte, opts baiduOCROption) error {
+	if opts.blankEntropyThreshold <= 0 {
+		return nil
+	}
+	if err := opts.checkPixels(imageBytes); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return err
+	}
+	if grayEntropy(img) < opts.blankEntropyThreshold {
+		return ErrBlankImage
+	}
+	return nil
+}
+
+// grayEntropy returns the Shannon entropy in bits of the gray level
+// histogram of img.
+func grayEntropy(img image.Image) (entropy float64) {
+	var histogram [256]int
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			histogram[color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y]++
+		}
+	}
+	total := float64(bounds.Dx() * bounds.Dy())
+	for _, n := range histogram {
+		if n > 0 {
+			p := float64(n) / total
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return
+}
+
+// crop returns the part of img inside rect, sharing pixels with img when the
+// image type supports it.
+func crop(img image.Image, rect image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+	newImg := image.NewRGBA(rect)
+	draw.Draw(newImg, rect, img, rect.Min, draw.Src)
+	return newImg
+}
+
+// gridCells splits bounds into rows×cols rectangles in row-major order.
+// Remainder pixels are spread over the cells so that their sizes differ by at
+// most one pixel.
+func gridCells(bounds image.Rectangle, rows, cols int) (cells []image.Rectangle) {
+	w, h := bounds.Dx(), bounds.Dy()
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			cells = append(cells, image.Rect(
+				bounds.Min.X+c*w/cols, bounds.Min.Y+r*h/rows,
+				bounds.Min.X+(c+1)*w/cols, bounds.Min.Y+(r+1)*h/rows,
+			))
+		}
+	}
 	return
 }
@@ -1,17 +1,36 @@
 package baiduocr_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/caiguanhao/baiduocr"
 )
 
 var APIKey string = os.Getenv("BAIDUOCR_APIKEY")
 
+// fakeJPEG is detected as JPEG but is not a decodable image.
+var fakeJPEG = []byte("\xff\xd8\xff fake jpeg")
+
 func Example_solveSimpleCaptcha() {
 	ocr := baiduocr.OCR{APIKey: APIKey, TimeoutInMilliseconds: 8000}
 	results, err := ocr.ParsePNGFile("test/fixtures/simple-captcha/3560.png", baiduocr.SetLanguageTypeToEnglish())
@@ -24,6 +43,23 @@ func Example_solveSimpleCaptcha() {
 	// 3560
 }
 
+func ExampleOCR_ParseCaptcha() {
+	ocr := baiduocr.OCR{APIKey: APIKey, TimeoutInMilliseconds: 8000}
+	captcha, err := ioutil.ReadFile("test/fixtures/simple-captcha/3560.png")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	answer, err := ocr.ParseCaptcha(captcha, baiduocr.SetLanguageTypeToEnglish())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(answer)
+	// Output:
+	// 3560
+}
+
 func Example_parseChineseText() {
 	ocr := baiduocr.OCR{APIKey: APIKey}
 	results, err := ocr.ParseJPEGFile("test/fixtures/chinese/hanzi.jpg")
@@ -88,3 +124,667 @@ func Example_parseHTTPResponse() {
 	// Output:
 	// 無
 }
+
+// newTestServer starts a fake Baidu OCR server which answers every request
+// with the words returned by handler, and an OCR client pointing to it.
+func newTestServer(t *testing.T, handler func(r *http.Request) []string) baiduocr.OCR {
+	return newWordsTestServer(t, func(r *http.Request) (words []baiduocr.Word) {
+		for _, text := range handler(r) {
+			words = append(words, baiduocr.Word{Text: text})
+		}
+		return
+	})
+}
+
+// newWordsTestServer is like newTestServer, with the bounding boxes of the
+// words returned in the rect field.
+func newWordsTestServer(t *testing.T, handler func(r *http.Request) []baiduocr.Word) baiduocr.OCR {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		type rect struct {
+			Height string `json:"height"`
+			Left   string `json:"left"`
+			Top    string `json:"top"`
+			Width  string `json:"width"`
+		}
+		type word struct {
+			Rect rect   `json:"rect"`
+			Word string `json:"word"`
+		}
+		ret := struct {
+			RetData []word `json:"retData"`
+		}{}
+		for _, w := range handler(r) {
+			ret.RetData = append(ret.RetData, word{rect{
+				fmt.Sprint(w.Rect.Dy()), fmt.Sprint(w.Rect.Min.X), fmt.Sprint(w.Rect.Min.Y), fmt.Sprint(w.Rect.Dx()),
+			}, w.Text})
+		}
+		json.NewEncoder(w).Encode(ret)
+	}))
+	t.Cleanup(server.Close)
+	return baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+}
+
+// requestImage decodes the image submitted in r.
+func requestImage(t *testing.T, r *http.Request) image.Image {
+	data, err := base64.StdEncoding.DecodeString(r.FormValue("image"))
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseGrid(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		img := requestImage(t, r)
+		if img == nil {
+			return nil
+		}
+		size := img.Bounds().Size()
+		return []string{fmt.Sprintf("%dx%d", size.X, size.Y)}
+	})
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	img.Set(0, 0, color.Black)
+	results, err := ocr.ParseGrid(encodePNG(t, img), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprint(results)
+	want := "[[33x25] [33x25] [34x25] [33x25] [33x25] [34x25]]"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// preprocessing applies to the whole image, not to each cell
+	results, err = ocr.ParseGrid(encodePNG(t, img), 1, 2, baiduocr.SetCropRect(image.Rect(10, 0, 70, 50)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(results), "[[30x50] [30x50]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseGridEmptyCell(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		if img := requestImage(t, r); img != nil && gray(img, 5, 5) < 128 {
+			return []string{"text"}
+		}
+		return nil
+	})
+	// a sprite of 2×2 cells, with text in the first and the last
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 10, 10), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(10, 10, 20, 20), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	results, err := ocr.ParseGrid(encodePNG(t, img), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[[text] [] [] [text]]" {
+		t.Errorf("got %s, want empty results for the empty cells", got)
+	}
+}
+
+func TestComputeImageHash(t *testing.T) {
+	var mutex sync.Mutex
+	var submitted []byte
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		data, _ := base64.StdEncoding.DecodeString(r.FormValue("image"))
+		mutex.Lock()
+		submitted = data
+		mutex.Unlock()
+		return []string{"ok"}
+	})
+	var record baiduocr.AuditRecord
+	ocr.AuditFunc = func(r baiduocr.AuditRecord) { record = r }
+	ocr.Cache, ocr.CacheTTL = baiduocr.NewMemoryCache(), time.Hour
+	// a PNG is hashed as the JPEG submitted, not as given
+	imageBytes := encodePNG(t, image.NewGray(image.Rect(0, 0, 20, 10)))
+	_, meta, err := ocr.ParseDetailed(imageBytes, baiduocr.ComputeImageHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	sum := sha256.Sum256(submitted)
+	mutex.Unlock()
+	want := hex.EncodeToString(sum[:])
+	if meta.ImageSHA256 != want || record.ImageSHA256 != want {
+		t.Errorf("got hashes %q and %q, want %q", meta.ImageSHA256, record.ImageSHA256, want)
+	}
+	if _, meta, _ = ocr.ParseDetailed(imageBytes, baiduocr.ComputeImageHash()); !meta.Cached || meta.ImageSHA256 != want {
+		t.Errorf("got hash %q of cached result, want %q", meta.ImageSHA256, want)
+	}
+	if _, meta, _ = ocr.ParseDetailed(imageBytes); meta.ImageSHA256 != "" {
+		t.Errorf("got hash %q without ComputeImageHash", meta.ImageSHA256)
+	}
+}
+
+func TestAuditFunc(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	var records []baiduocr.AuditRecord
+	ocr.AuditFunc = func(record baiduocr.AuditRecord) { records = append(records, record) }
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetLanguageTypeToEnglish()); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d audit records, want 1", len(records))
+	}
+	record := records[0]
+	if record.Endpoint != ocr.APIPath {
+		t.Errorf("endpoint = %q, want %q", record.Endpoint, ocr.APIPath)
+	}
+	size := base64.StdEncoding.EncodedLen(len(fakeJPEG))
+	if got, want := record.Params.Get("image"), fmt.Sprintf("[redacted %d bytes]", size); got != want {
+		t.Errorf("image = %q, want %q", got, want)
+	}
+	if got := record.Params.Get("languagetype"); got != "ENG" {
+		t.Errorf("languagetype = %q, want ENG", got)
+	}
+	for _, key := range []string{"fromdevice", "clientip", "detecttype", "imagetype", "version", "sizetype"} {
+		if record.Params.Get(key) == "" {
+			t.Errorf("%s is missing", key)
+		}
+	}
+	for key, values := range record.Params {
+		for _, value := range values {
+			if strings.Contains(value, ocr.APIKey) {
+				t.Errorf("%s contains the API key", key)
+			}
+		}
+	}
+}
+
+func TestSkipBlankImages(t *testing.T) {
+	calls := 0
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		calls++
+		return []string{"text"}
+	})
+	blank := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(blank, blank.Bounds(), &image.Uniform{color.RGBA{250, 250, 250, 255}}, image.Point{}, draw.Src)
+	results, err := ocr.ParseImage(encodePNG(t, blank), baiduocr.SkipBlankImages(0.5))
+	if !errors.Is(err, baiduocr.ErrBlankImage) || results != nil {
+		t.Errorf("got %v, %v, want ErrBlankImage", results, err)
+	}
+	if calls != 0 {
+		t.Errorf("Baidu OCR called %d times for a blank image", calls)
+	}
+
+	text := image.NewRGBA(blank.Bounds())
+	draw.Draw(text, text.Bounds(), blank, image.Point{}, draw.Src)
+	draw.Draw(text, image.Rect(8, 24, 56, 40), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	if _, err := ocr.ParseImage(encodePNG(t, text), baiduocr.SkipBlankImages(0.5)); err != nil {
+		t.Error(err)
+	}
+	if _, err := ocr.ParseImage(encodePNG(t, blank)); err != nil {
+		t.Error(err)
+	}
+	if calls != 2 {
+		t.Errorf("Baidu OCR called %d times, want 2", calls)
+	}
+}
+
+func TestSetRequestID(t *testing.T) {
+	var header string
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		header = r.Header.Get("X-Request-Id")
+		return nil
+	})
+	var record baiduocr.AuditRecord
+	ocr.AuditFunc = func(r baiduocr.AuditRecord) { record = r }
+	_, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetRequestID("trace-1"))
+	if header != "trace-1" || record.RequestID != "trace-1" {
+		t.Errorf("header %q, audit record %q, want trace-1", header, record.RequestID)
+	}
+	if err == nil || !strings.Contains(err.Error(), "trace-1") {
+		t.Errorf("error %v does not contain the request ID", err)
+	}
+
+	ocr.ParseJPEG(fakeJPEG)
+	if len(header) != 36 || header != record.RequestID {
+		t.Errorf("got generated ID %q, audit record %q", header, record.RequestID)
+	}
+	ocr.AuditFunc = nil
+	if ocr.ParseJPEG(fakeJPEG); header != "" {
+		t.Errorf("got ID %q without audit func", header)
+	}
+}
+
+func TestDefaultPNGBackground(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	transparent := encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 16, 16)))
+	for _, test := range []struct {
+		name    string
+		def     color.Color
+		options []baiduocr.BaiduOCROption
+		want    uint8
+	}{
+		{"none", nil, nil, 0},
+		{"client default", color.White, nil, 255},
+		{"per-call option", color.White, []baiduocr.BaiduOCROption{baiduocr.SetPNGBackgroundColorRGBA(128, 128, 128, 255)}, 128},
+	} {
+		ocr.DefaultPNGBackground = test.def
+		if _, err := ocr.ParseImage(transparent, test.options...); err != nil {
+			t.Fatal(err)
+		}
+		if g := gray(submitted(), 8, 8); absDiff(g, test.want) > 5 {
+			t.Errorf("%s: background is %d, want %d", test.name, g, test.want)
+		}
+	}
+}
+
+func TestSetURLSafeBase64(t *testing.T) {
+	// a JPEG signature and bytes whose standard base64 encoding is "/9j/+/+/"
+	imageBytes := []byte{0xff, 0xd8, 0xff, 0xfb, 0xff, 0xbf}
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.FormValue("image")}
+	})
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult(r.FormValue("image"))
+	})
+	parsers := map[string]func(...baiduocr.BaiduOCROption) ([]string, error){
+		"OCR": func(options ...baiduocr.BaiduOCROption) ([]string, error) {
+			return ocr.ParseJPEG(imageBytes, options...)
+		},
+		"AipOCR": func(options ...baiduocr.BaiduOCROption) ([]string, error) {
+			return aip.GeneralBasic(imageBytes, options...)
+		},
+	}
+	for name, parse := range parsers {
+		standard, err := parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		urlSafe, err := parse(baiduocr.SetURLSafeBase64())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.NewReplacer("+", "-", "/", "_").Replace(standard[0]); urlSafe[0] != want {
+			t.Errorf("%s: image = %q, want %q", name, urlSafe[0], want)
+		}
+	}
+	if results, _ := ocr.ParseJPEG(imageBytes, baiduocr.SetURLSafeBase64()); results[0] != "_9j_-_-_" {
+		t.Errorf("image = %q, want _9j_-_-_", results[0])
+	}
+}
+
+func TestFormContentLength(t *testing.T) {
+	// every byte value, so that the base64 encoding has + and / to escape
+	imageBytes := []byte("\xff\xd8\xff")
+	for i := 0; i < 1000; i++ {
+		imageBytes = append(imageBytes, byte(i))
+	}
+	var form url.Values
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("content length = %d, body has %d bytes", r.ContentLength, len(body))
+		}
+		if form, err = url.ParseQuery(string(body)); err != nil {
+			t.Fatal(err)
+		}
+		return []string{"ok"}
+	})
+	for _, test := range []struct {
+		encoding *base64.Encoding
+		options  []baiduocr.BaiduOCROption
+	}{
+		{base64.StdEncoding, nil},
+		{base64.URLEncoding, []baiduocr.BaiduOCROption{baiduocr.SetURLSafeBase64()}},
+	} {
+		if _, err := ocr.ParseJPEG(imageBytes, test.options...); err != nil {
+			t.Fatal(err)
+		}
+		if got := form.Get("image"); got != test.encoding.EncodeToString(imageBytes) {
+			t.Errorf("image = %q", got)
+		}
+		if got := form.Get("languagetype"); got != "CHN_ENG" {
+			t.Errorf("languagetype = %q", got)
+		}
+	}
+}
+
+func TestSetFormFieldOrder(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(body)) != r.ContentLength {
+			t.Errorf("body has %d bytes, Content-Length is %d", len(body), r.ContentLength)
+		}
+		var keys []string
+		for _, field := range strings.Split(string(body), "&") {
+			keys = append(keys, strings.SplitN(field, "=", 2)[0])
+		}
+		return []string{strings.Join(keys, ",")}
+	})
+	for _, test := range []struct {
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{nil, "clientip,detecttype,fromdevice,imagetype,languagetype,sizetype,version,image"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetFormFieldOrder("version", "image", "fromdevice", "unknown")},
+			"version,image,fromdevice,clientip,detecttype,imagetype,languagetype,sizetype"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetFormFieldOrder("version", "fromdevice")},
+			"version,fromdevice,clientip,detecttype,imagetype,languagetype,sizetype,image"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetFormFieldOrder("image", "version")},
+			"image,version,clientip,detecttype,fromdevice,imagetype,languagetype,sizetype"},
+	} {
+		results, err := ocr.ParseJPEG(fakeJPEG, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0] != test.want {
+			t.Errorf("got fields %s, want %s", results[0], test.want)
+		}
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	calls := 0
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		calls++
+		return []string{r.FormValue("version")}
+	})
+	for _, test := range []struct {
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{nil, "v1"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetVersion("v2")}, "v2"},
+	} {
+		results, err := ocr.ParseJPEG(fakeJPEG, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0] != test.want {
+			t.Errorf("version = %q, want %q", results[0], test.want)
+		}
+	}
+	calls = 0
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetVersion("")); err == nil || !strings.Contains(err.Error(), "version must not be empty") {
+		t.Errorf("got %v, want the empty version rejected", err)
+	}
+	if calls != 0 {
+		t.Error("request sent with an empty version")
+	}
+}
+
+func TestSetClientVersion(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.UserAgent()}
+	})
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult(r.UserAgent())
+	})
+	for _, test := range []struct {
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{nil, "baiduocr/" + baiduocr.Version},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetClientVersion("myapp/2.1")}, "myapp/2.1 baiduocr/" + baiduocr.Version},
+	} {
+		for _, parse := range []func([]byte, ...baiduocr.BaiduOCROption) ([]string, error){ocr.ParseJPEG, aip.GeneralBasic} {
+			results, err := parse(fakeJPEG, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if results[0] != test.want {
+				t.Errorf("User-Agent = %q, want %q", results[0], test.want)
+			}
+		}
+	}
+}
+
+func TestParseImageURL(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.FormValue("imagetype"), r.FormValue("image")}
+	})
+	results, err := ocr.ParseImageURL("https://example.com/captcha.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(results, " "), "2 https://example.com/captcha.jpg"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetImageType(baiduocr.ImageTypeURL)); err == nil {
+		t.Error("want error submitting image bytes as URL")
+	}
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetImageType("3")); err == nil {
+		t.Error("want error for unknown image type")
+	}
+	if results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetImageType(baiduocr.ImageTypeBase64)); err != nil || results[0] != "1" {
+		t.Errorf("got %q, %v, want image type 1", results, err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestImage(t, r).Bounds().Size() != image.Pt(1, 1) {
+			t.Error("want a 1x1 image")
+		}
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+
+	response = `{"errNum":0,"errMsg":"success","retData":[]}`
+	if err := ocr.Ping(); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	response = `{"errNum":300202,"errMsg":"Missing apikey"}`
+	if err := ocr.Ping(); !errors.Is(err, baiduocr.ErrInvalidAPIKey) {
+		t.Errorf("got %v, want ErrInvalidAPIKey", err)
+	}
+	server.Close()
+	if err := ocr.Ping(); err == nil || errors.Is(err, baiduocr.ErrInvalidAPIKey) {
+		t.Errorf("got %v, want unavailable error", err)
+	}
+}
+
+func TestAcceptHeaders(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.Header.Get("Accept"), r.Header.Get("Accept-Language")}
+	})
+	for _, test := range []struct {
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{nil, "application/json|"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetAccept("*/*"), baiduocr.SetAcceptLanguage("zh-CN")}, "*/*|zh-CN"},
+	} {
+		results, err := ocr.ParseJPEG(fakeJPEG, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(results, "|"); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestSetAPIKeyHeader(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.Header.Get("apikey"), r.Header.Get("Authorization")}
+	})
+	results, err := ocr.ParseJPEG(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(results, "|"); got != "test-api-key|" {
+		t.Errorf("got %q, want key in apikey header", got)
+	}
+	if results, err = ocr.ParseJPEG(fakeJPEG, baiduocr.SetAPIKeyHeader("Authorization", "Bearer ")); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(results, "|"); got != "|Bearer test-api-key" {
+		t.Errorf("got %q, want key in Authorization header", got)
+	}
+}
+
+func TestParseCaptcha(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		if r.FormValue("detecttype") != "Recognize" {
+			t.Errorf("detecttype = %s, want Recognize", r.FormValue("detecttype"))
+		}
+		return []baiduocr.Word{
+			{Text: "60", Rect: image.Rect(40, 0, 80, 30)},
+			{Text: "35", Rect: image.Rect(0, 0, 40, 30)},
+		}
+	})
+	captcha, err := ioutil.ReadFile("test/fixtures/simple-captcha/3560.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer, err := ocr.ParseCaptcha(captcha, baiduocr.SetLanguageTypeToEnglish())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "3560" {
+		t.Errorf("got %q, want 3560", answer)
+	}
+}
+
+func TestParseFileString(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"35", "60"}
+	})
+	for _, parse := range []func(string, string, ...baiduocr.BaiduOCROption) (string, error){
+		ocr.ParseImageFileString, ocr.ParsePNGFileString,
+	} {
+		result, err := parse("test/fixtures/simple-captcha/3560.png", " ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != "35 60" {
+			t.Errorf("got %q", result)
+		}
+	}
+	if result, err := ocr.ParseJPEGFileString("test/fixtures/chinese/hanzi.jpg", ""); err != nil || result != "3560" {
+		t.Errorf("got %q, %v", result, err)
+	}
+	if _, err := ocr.ParseImageFileString("test/fixtures/missing.png", " "); err == nil {
+		t.Error("got no error for missing file")
+	}
+}
+
+func TestResultMetaHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Quota-Remaining", "42")
+		w.Write([]byte(`{"retData":[{"word":"ok"}]}`))
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL, Cache: baiduocr.NewMemoryCache(), CacheTTL: time.Hour}
+	_, meta, err := ocr.ParseDetailed(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := meta.Headers.Get("X-Quota-Remaining"); got != "42" {
+		t.Errorf("got X-Quota-Remaining %q, want 42", got)
+	}
+	if _, meta, _ = ocr.ParseDetailed(fakeJPEG); !meta.Cached || meta.Headers != nil {
+		t.Errorf("got headers %v for cached result", meta.Headers)
+	}
+}
+
+func TestDefaultLanguage(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.FormValue("languagetype")}
+	})
+	for _, test := range []struct {
+		name    string
+		def     string
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{"none", "", nil, baiduocr.LanguageChinese},
+		{"client default", baiduocr.LanguageEnglish, nil, baiduocr.LanguageEnglish},
+		{"per-call option", baiduocr.LanguageEnglish, []baiduocr.BaiduOCROption{baiduocr.SetLanguageTypeToJapanese()}, baiduocr.LanguageJapanese},
+	} {
+		ocr.DefaultLanguage = test.def
+		results, err := ocr.ParseJPEG(fakeJPEG, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(results, ""); got != test.want {
+			t.Errorf("%s: got language type %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNormalizedRect(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{{Text: "box", Rect: image.Rect(10, 5, 60, 45)}, {Text: "unlocated"}}
+	})
+	words, meta, err := ocr.ParseDetailed(encodePNG(t, image.NewGray(image.Rect(0, 0, 100, 50))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ImageSize != image.Pt(100, 50) {
+		t.Fatalf("got image size %v", meta.ImageSize)
+	}
+	if got, want := words[0].NormalizedRect, (baiduocr.NormalizedRect{Left: 0.1, Top: 0.1, Right: 0.6, Bottom: 0.9}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := words[0].NormalizedRect.Scale(image.Pt(200, 100)); got != image.Rect(20, 10, 120, 90) {
+		t.Errorf("scaled to %v", got)
+	}
+	if words[1].NormalizedRect != (baiduocr.NormalizedRect{}) {
+		t.Errorf("got %+v for a word without box", words[1].NormalizedRect)
+	}
+}
+
+func TestPayloadWarnBytes(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	var warnings []string
+	ocr.LogFunc = func(format string, v ...interface{}) { warnings = append(warnings, fmt.Sprintf(format, v...)) }
+	if _, err := ocr.ParseJPEG(fakeJPEG); err != nil || len(warnings) != 0 {
+		t.Fatalf("got %v, warnings %q without a threshold", err, warnings)
+	}
+	ocr.PayloadWarnBytes = 10
+	if _, err := ocr.ParseJPEG(fakeJPEG); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], ocr.APIPath) || !strings.Contains(warnings[0], "threshold of 10 bytes") {
+		t.Errorf("got warnings %q", warnings)
+	}
+	ocr.PayloadWarnBytes = 1 << 20
+	ocr.ParseJPEG(fakeJPEG)
+	if len(warnings) != 1 {
+		t.Errorf("got warnings %q below the threshold", warnings)
+	}
+
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult("ok")
+	})
+	warnings = nil
+	aip.LogFunc = ocr.LogFunc
+	aip.PayloadWarnBytes = 10
+	if _, err := aip.GeneralBasic(fakeJPEG); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || strings.Contains(warnings[0], "token") {
+		t.Errorf("got warnings %q, want one without the access token", warnings)
+	}
+}
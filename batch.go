@@ -0,0 +1,177 @@
+package baiduocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const _BATCH_CONCURRENCY = 4
+
+// FileResult is the result of one file of a batch.
+type FileResult struct {
+	Filename string
+	Results  []string
+	// Err is the error reading, converting or recognizing the file, or nil
+	Err error
+}
+
+// Unsupported reports whether the file failed because its format is not
+// supported, as opposed to a read or API error. Such files can be converted
+// and submitted again.
+func (result FileResult) Unsupported() bool {
+	return errors.Is(result.Err, ErrUnsupportedFormat)
+}
+
+// Cancelled reports whether the file was not recognized because the context
+// of the batch was cancelled or timed out, before or while it was processed.
+func (result FileResult) Cancelled() bool {
+	return errors.Is(result.Err, context.Canceled) || errors.Is(result.Err, context.DeadlineExceeded)
+}
+
+// Read text from image files of any supported type, with at most concurrency
+// files processed at the same time. A failing file does not abort the batch,
+// its error is reported in its FileResult. The results are in the same order
+// as filenames. If ctx is cancelled, the files completed so far keep their
+// results, the files not yet started or still in flight are reported with
+// the context error, see FileResult.Cancelled, and the context error is
+// returned once the files in flight have stopped, so a batch can be cut short
+// without losing the work done.
+func (ocr OCR) ParseFiles(ctx context.Context, filenames []string, concurrency int, options ...BaiduOCROption) (results []FileResult, err error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	options = append(options[:len(options):len(options)], SetContext(ctx))
+	results = make([]FileResult, len(filenames))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, filename := range filenames {
+		results[i].Filename = filename
+		if ctx.Err() != nil {
+			// select picks at random if a slot is free too
+			results[i].Err = ctx.Err()
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(result *FileResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result.Results, result.Err = ocr.ParseImageFile(result.Filename, options...)
+		}(&results[i])
+	}
+	wg.Wait()
+	err = ctx.Err()
+	return
+}
+
+// Same as ParseFiles, but returns the results keyed by filename, including
+// the cancelled ones.
+func (ocr OCR) ParseFilesMap(ctx context.Context, filenames []string, concurrency int, options ...BaiduOCROption) (results map[string]FileResult, err error) {
+	var list []FileResult
+	list, err = ocr.ParseFiles(ctx, filenames, concurrency, options...)
+	results = make(map[string]FileResult, len(list))
+	for _, result := range list {
+		results[result.Filename] = result
+	}
+	return
+}
+
+// imageExtensions are the content types of the file extensions ParseDir
+// reads, those of formats without a decoder being skipped.
+var imageExtensions = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// Read text from the image files in dir, and in its subdirectories if
+// recursive, with ParseFiles. Files are picked by their extension, ignoring
+// case: .jpg, .jpeg, .png and .gif, and .webp with the webp build tag; other
+// files are skipped. The results of the files recognized are keyed by their
+// path, starting with dir. The errors of the other files, and of the
+// directories which could not be read, are joined into err, each with its
+// path, so the results are complete only if err is nil. If ctx is cancelled,
+// err wraps the context error, see ParseFiles.
+func (ocr OCR) ParseDir(ctx context.Context, dir string, recursive bool, concurrency int, options ...BaiduOCROption) (results map[string][]string, err error) {
+	var filenames []string
+	var errs []error
+	walkErr := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := imageDecoders[imageExtensions[strings.ToLower(filepath.Ext(path))]]; ok {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	sort.Strings(filenames)
+	list, ctxErr := ocr.ParseFiles(ctx, filenames, concurrency, options...)
+	results = map[string][]string{}
+	for _, result := range list {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Filename, result.Err))
+			continue
+		}
+		results[result.Filename] = result.Results
+	}
+	if ctxErr != nil {
+		errs = append(errs, ctxErr)
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// Read text from several JPEG/PNG images, returning the results in the same
+// order as images. Neither the apistore endpoint nor the AIP endpoints accept
+// more than one image per request, so one request is sent per image, at most
+// four at a time. If any image fails, the error of the first failing image is
+// returned, along with the results of the others.
+func (ocr OCR) ParseBatch(images [][]byte, options ...BaiduOCROption) (results [][]string, err error) {
+	results = make([][]string, len(images))
+	errs := make([]error, len(images))
+	sem := make(chan struct{}, _BATCH_CONCURRENCY)
+	var wg sync.WaitGroup
+	for i := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = ocr.ParseImage(images[i], options...)
+		}(i)
+	}
+	wg.Wait()
+	for i, e := range errs {
+		if e != nil {
+			err = fmt.Errorf("image %d: %w", i, e)
+			break
+		}
+	}
+	return
+}
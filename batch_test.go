@@ -0,0 +1,170 @@
+package baiduocr_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseFilesCategorizesErrors(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		if r.FormValue("languagetype") == "JAP" {
+			return nil
+		}
+		return []string{"ok"}
+	})
+	dir := t.TempDir()
+	svg := filepath.Join(dir, "image.svg")
+	if err := ioutil.WriteFile(svg, []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filenames := []string{"test/fixtures/chinese/hanzi.jpg", svg, "test/fixtures/simple-captcha/3560.png"}
+	results, err := ocr.ParseFiles(context.Background(), filenames, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if result.Filename != filenames[i] {
+			t.Errorf("result %d is for %s, want %s", i, result.Filename, filenames[i])
+		}
+	}
+	if results[0].Err != nil || results[0].Results[0] != "ok" {
+		t.Errorf("jpeg: %v %v", results[0].Results, results[0].Err)
+	}
+	if !results[1].Unsupported() || !errors.Is(results[1].Err, baiduocr.ErrUnsupportedFormat) {
+		t.Errorf("svg: want unsupported format error, got %v", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Unsupported() {
+		t.Errorf("png: %v", results[2].Err)
+	}
+
+	byName, err := ocr.ParseFilesMap(context.Background(), filenames[:1], 1, baiduocr.SetLanguageTypeToJapanese())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := byName[filenames[0]]; result.Err == nil || result.Unsupported() {
+		t.Errorf("want an API error, got %v", result.Err)
+	}
+}
+
+func TestParseBatch(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		if r.FormValue("image") == base64.StdEncoding.EncodeToString(fakeJPEG) {
+			return []string{"fake"}
+		}
+		return []string{"real"}
+	})
+	hanzi, err := ioutil.ReadFile("test/fixtures/chinese/hanzi.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ocr.ParseBatch([][]byte{fakeJPEG, hanzi, fakeJPEG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[[fake] [real] [fake]]" {
+		t.Errorf("got %s", got)
+	}
+	results, err = ocr.ParseBatch([][]byte{hanzi, []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)})
+	if !errors.Is(err, baiduocr.ErrUnsupportedFormat) || len(results[0]) != 1 {
+		t.Errorf("got %v, %v", results, err)
+	}
+}
+
+func TestParseFilesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int32
+	release := make(chan struct{})
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return []string{"first"}
+		}
+		// cancel midway, with the second file in flight
+		cancel()
+		<-release
+		return nil
+	})
+	filenames := []string{"test/fixtures/chinese/hanzi.jpg", "test/fixtures/simple-captcha/3560.png", "test/fixtures/chinese/hanzi.jpg"}
+	results, err := ocr.ParseFiles(ctx, filenames, 1)
+	close(release)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context canceled", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Err != nil || fmt.Sprint(results[0].Results) != "[first]" || results[0].Cancelled() {
+		t.Errorf("completed file: %v %v", results[0].Results, results[0].Err)
+	}
+	for _, result := range results[1:] {
+		if !result.Cancelled() || result.Results != nil || result.Filename == "" {
+			t.Errorf("pending file %s: %v %v, want cancelled", result.Filename, result.Results, result.Err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("sent %d requests, want none after cancel", n)
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	captcha, err := ioutil.ReadFile("test/fixtures/simple-captcha/3560.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"a.png":       captcha,
+		"notes.txt":   []byte("not an image"),
+		"bad.png":     []byte("not an image either"),
+		"sub/b.PNG":   captcha,
+		"sub/c/d.jpg": captcha,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := ocr.ParseDir(context.Background(), dir, true, 2)
+	if !errors.Is(err, baiduocr.ErrUnsupportedFormat) || !strings.Contains(err.Error(), filepath.Join(dir, "bad.png")) {
+		t.Errorf("got %v, want the error of bad.png", err)
+	}
+	var names []string
+	for name, texts := range results {
+		rel, _ := filepath.Rel(dir, name)
+		names = append(names, filepath.ToSlash(rel)+"="+strings.Join(texts, ""))
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, " "); got != "a.png=ok sub/b.PNG=ok sub/c/d.jpg=ok" {
+		t.Errorf("got %s", got)
+	}
+
+	if results, _ = ocr.ParseDir(context.Background(), dir, false, 2); len(results) != 1 {
+		t.Errorf("got %d results, want only a.png without recursion", len(results))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ocr.ParseDir(ctx, dir, true, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context error", err)
+	}
+	if _, err := ocr.ParseDir(context.Background(), filepath.Join(dir, "missing"), true, 2); err == nil {
+		t.Error("got no error for a missing directory")
+	}
+}
@@ -0,0 +1,60 @@
+package baiduocr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// ResultCache stores recognized words by key, which is derived from the
+	// SHA-256 hash of the submitted image and the request parameters. It must
+	// be safe for concurrent use.
+	ResultCache interface {
+		Get(key string) (words []Word, storedAt time.Time, found bool)
+		Set(key string, words []Word, storedAt time.Time)
+	}
+
+	// MemoryCache is a ResultCache keeping every entry in memory.
+	MemoryCache struct {
+		mutex   sync.Mutex
+		entries map[string]memoryCacheEntry
+	}
+
+	memoryCacheEntry struct {
+		words    []Word
+		storedAt time.Time
+	}
+)
+
+// Create an empty in-memory result cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryCacheEntry{}}
+}
+
+func (cache *MemoryCache) Get(key string) (words []Word, storedAt time.Time, found bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	entry, found := cache.entries[key]
+	return entry.words, entry.storedAt, found
+}
+
+func (cache *MemoryCache) Set(key string, words []Word, storedAt time.Time) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.entries[key] = memoryCacheEntry{words, storedAt}
+}
+
+// cacheKey returns the key of the result of submitting the image to the
+// endpoint at path, made of everything in the request which changes the
+// answer of Baidu.
+func cacheKey(path string, imageBytes []byte, opts baiduOCROption) string {
+	hash := sha256.New()
+	hash.Write(imageBytes)
+	for _, param := range []string{path, opts.detectType(), opts.languageType, opts.version, opts.imageType, strconv.FormatBool(opts.urlSafeBase64)} {
+		hash.Write([]byte("\x00" + param))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,81 @@
+package baiduocr_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestCacheServeStaleOnError(t *testing.T) {
+	calls := 0
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		calls++
+		if calls > 1 {
+			return nil
+		}
+		return []string{"fresh"}
+	})
+	ocr.Cache = baiduocr.NewMemoryCache()
+
+	words, meta, err := ocr.ParseDetailed(fakeJPEG, baiduocr.ServeStaleOnError())
+	if err != nil || meta.Stale || meta.Cached {
+		t.Fatalf("first call: %v, %+v", err, meta)
+	}
+	if _, err := ocr.ParseJPEG(fakeJPEG); err == nil {
+		t.Error("want an error without ServeStaleOnError")
+	}
+	words, meta, err = ocr.ParseDetailed(fakeJPEG, baiduocr.ServeStaleOnError())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || words[0].Text != "fresh" {
+		t.Errorf("got %v, want the cached words", words)
+	}
+	if !meta.Stale || !meta.Cached || meta.StaleCause == nil {
+		t.Errorf("got meta %+v, want stale with cause", meta)
+	}
+	if _, _, err := ocr.ParseDetailed(fakeJPEG, baiduocr.ServeStaleOnError(), baiduocr.SetLanguageTypeToEnglish()); err == nil {
+		t.Error("want an error for an uncached language")
+	}
+
+	ocr.CacheTTL = time.Hour
+	before := calls
+	if _, meta, err = ocr.ParseDetailed(fakeJPEG); err != nil || !meta.Cached || meta.Stale {
+		t.Errorf("got %v, %+v, want a fresh cache hit", err, meta)
+	}
+	if calls != before {
+		t.Error("Baidu OCR called for a fresh cache entry")
+	}
+}
+
+func TestCacheKeyedByRequest(t *testing.T) {
+	cache := baiduocr.NewMemoryCache()
+	newOCR := func(name string) baiduocr.OCR {
+		ocr := newTestServer(t, func(r *http.Request) []string {
+			return []string{name + " " + r.FormValue("detecttype")}
+		})
+		ocr.Cache, ocr.CacheTTL = cache, time.Hour
+		return ocr
+	}
+	first, second := newOCR("first"), newOCR("second")
+	for _, test := range []struct {
+		ocr     baiduocr.OCR
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{first, nil, "first LocateRecognize"},
+		{second, nil, "second LocateRecognize"},
+		{first, []baiduocr.BaiduOCROption{baiduocr.SingleLineMode()}, "first Recognize"},
+		{first, nil, "first LocateRecognize"},
+	} {
+		results, err := test.ocr.ParseJPEG(fakeJPEG, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0] != test.want {
+			t.Errorf("got %q, want %q", results[0], test.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package baiduocr
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+type (
+	// ClientOption configures the HTTP client created by NewOCR and
+	// NewAipOCR.
+	ClientOption struct {
+		f func(*clientOption)
+	}
+
+	clientOption struct {
+		maxIdleConns    int
+		maxConnsPerHost int
+		idleConnTimeout time.Duration
+		keepAlive       time.Duration
+
+		insecureSkipVerify bool
+	}
+)
+
+const (
+	_DEFAULT_MAX_IDLE_CONNS    = 100
+	_DEFAULT_IDLE_CONN_TIMEOUT = 90 * time.Second
+	_DEFAULT_KEEP_ALIVE        = 30 * time.Second
+)
+
+// Create an OCR client which keeps connections to Baidu OCR open between
+// requests, instead of creating a new HTTP client for each request as a bare
+// OCR struct does. Without options, up to 100 idle connections are kept, all
+// of them may go to the same host, for up to 90 seconds, and the number of
+// connections per host is not limited. To use your own HTTP client, set the
+// HTTPClient field instead, these options do not apply to it.
+func NewOCR(apiKey string, options ...ClientOption) OCR {
+	return OCR{APIKey: apiKey, HTTPClient: newHTTPClient(options)}
+}
+
+// Option to set the maximum number of idle connections kept open, to the
+// same host or in total, default is 100. Raise it together with the
+// concurrency of batch methods so that every worker can reuse a connection.
+func WithMaxIdleConns(n int) ClientOption {
+	return ClientOption{func(option *clientOption) { option.maxIdleConns = n }}
+}
+
+// Option to limit the number of connections per host, including those in
+// use, default is 0 meaning no limit. Requests over the limit wait for a free
+// connection.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return ClientOption{func(option *clientOption) { option.maxConnsPerHost = n }}
+}
+
+// Option to set how long an idle connection is kept open, default is 90s.
+func WithIdleConnTimeout(d time.Duration) ClientOption {
+	return ClientOption{func(option *clientOption) { option.idleConnTimeout = d }}
+}
+
+// Option to set the interval of TCP keep-alive probes on connections,
+// default is 30s, negative disables them. Probes let the operating system
+// notice dead connections and keep NAT and firewall entries open, but load
+// balancers usually close connections idle at the HTTP level whatever the
+// probes, see OCR.KeepWarm for that.
+func WithKeepAlive(interval time.Duration) ClientOption {
+	return ClientOption{func(option *clientOption) { option.keepAlive = interval }}
+}
+
+// Option to skip the verification of TLS certificates, for self-hosted
+// gateways, proxies intercepting TLS and test endpoints with self-signed
+// certificates.
+//
+// WARNING: this is insecure. Certificates are not verified at all, so anyone
+// on the network path can impersonate the endpoint and read or change the
+// traffic, including the API key and the images. Never use it to reach
+// Baidu OCR over the internet; prefer adding the certificate of the proxy to
+// the trusted roots of a custom HTTPClient. Certificates are verified by
+// default.
+func WithInsecureSkipVerify() ClientOption {
+	return ClientOption{func(option *clientOption) { option.insecureSkipVerify = true }}
+}
+
+// Keep a connection to Baidu OCR open by sending a HEAD request to the API
+// path every interval, until ctx is done, for services calling Baidu OCR
+// only now and then: a connection closed as idle by the load balancer of
+// Baidu costs a new TCP and TLS handshake to the next call. Run it in its own
+// goroutine. The requests do not recognize anything, so they cost no quota,
+// but they keep a connection open even when there is no work, and interval
+// must be shorter than the idle timeouts of the load balancer and
+// WithIdleConnTimeout to be of any use; a minute is usually enough. Failed
+// requests are ignored. It returns the error of ctx, or an error right away
+// if HTTPClient is nil, as there would be no connection to reuse.
+func (ocr OCR) KeepWarm(ctx context.Context, interval time.Duration) error {
+	if ocr.HTTPClient == nil {
+		return errors.New("KeepWarm needs HTTPClient, use NewOCR")
+	}
+	path := ocr.APIPath
+	if len(path) == 0 {
+		path = _API_PATH
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		req, err := http.NewRequest("HEAD", path, nil)
+		if err != nil {
+			return err
+		}
+		doRequest(ocr.HTTPClient, ocr.TimeoutInMilliseconds, ocr.TimeoutMode, ocr.Signer, req.WithContext(ctx))
+	}
+}
+
+func newHTTPClient(options []ClientOption) *http.Client {
+	opts := clientOption{
+		maxIdleConns:    _DEFAULT_MAX_IDLE_CONNS,
+		idleConnTimeout: _DEFAULT_IDLE_CONN_TIMEOUT,
+		keepAlive:       _DEFAULT_KEEP_ALIVE,
+	}
+	for _, option := range options {
+		option.f(&opts)
+	}
+	var tlsConfig *tls.Config
+	if opts.insecureSkipVerify {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: opts.keepAlive,
+			}).DialContext,
+			MaxIdleConns:        opts.maxIdleConns,
+			MaxIdleConnsPerHost: opts.maxIdleConns,
+			MaxConnsPerHost:     opts.maxConnsPerHost,
+			IdleConnTimeout:     opts.idleConnTimeout,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     tlsConfig,
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package baiduocr_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestKeepWarm(t *testing.T) {
+	var mutex sync.Mutex
+	heads := 0
+	conns := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.Method == "HEAD" {
+			heads++
+		}
+		conns[r.RemoteAddr] = true
+	}))
+	defer server.Close()
+
+	ocr := baiduocr.NewOCR("test-api-key", baiduocr.WithKeepAlive(time.Second))
+	ocr.APIPath = server.URL
+	ctx, cancel := context.WithTimeout(context.Background(), 110*time.Millisecond)
+	defer cancel()
+	if err := ocr.KeepWarm(ctx, 20*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if heads < 3 || len(conns) != 1 {
+		t.Errorf("got %d HEAD requests over %d connections, want at least 3 over 1", heads, len(conns))
+	}
+
+	if err := (baiduocr.OCR{}).KeepWarm(ctx, time.Second); err == nil {
+		t.Error("want error without HTTPClient")
+	}
+}
+
+func TestWithInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"retData":[{"word":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	ocr := baiduocr.NewOCR("test-api-key")
+	ocr.APIPath = server.URL
+	if _, err := ocr.ParseJPEG(fakeJPEG); err == nil {
+		t.Error("want certificate error by default")
+	}
+	ocr = baiduocr.NewOCR("test-api-key", baiduocr.WithInsecureSkipVerify())
+	ocr.APIPath = server.URL
+	if results, err := ocr.ParseJPEG(fakeJPEG); err != nil || len(results) != 1 {
+		t.Errorf("got %v, %v", results, err)
+	}
+}
+
+type countingTransport struct{ calls int }
+
+func (transport *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport.calls++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientOptions(t *testing.T) {
+	transportOf := func(client *http.Client) *http.Transport {
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("transport is %T", client.Transport)
+		}
+		return transport
+	}
+	transport := transportOf(baiduocr.NewOCR("key").HTTPClient)
+	if transport.MaxIdleConns != 100 || transport.MaxIdleConnsPerHost != 100 || transport.MaxConnsPerHost != 0 || transport.IdleConnTimeout != 90*time.Second {
+		t.Errorf("default transport: %d idle, %d idle per host, %d per host, %v", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost, transport.IdleConnTimeout)
+	}
+	options := []baiduocr.ClientOption{baiduocr.WithMaxIdleConns(8), baiduocr.WithMaxConnsPerHost(4), baiduocr.WithIdleConnTimeout(time.Minute)}
+	for _, client := range []*http.Client{baiduocr.NewOCR("key", options...).HTTPClient, baiduocr.NewAipOCR("ak", "sk", options...).HTTPClient} {
+		transport := transportOf(client)
+		if transport.MaxIdleConns != 8 || transport.MaxIdleConnsPerHost != 8 || transport.MaxConnsPerHost != 4 || transport.IdleConnTimeout != time.Minute {
+			t.Errorf("transport: %d idle, %d idle per host, %d per host, %v", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost, transport.IdleConnTimeout)
+		}
+	}
+
+	ocr := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	custom := &countingTransport{}
+	ocr.HTTPClient = &http.Client{Transport: custom}
+	if _, err := ocr.ParseJPEG(fakeJPEG); err != nil {
+		t.Fatal(err)
+	}
+	if custom.calls != 1 {
+		t.Errorf("custom HTTPClient used %d times, want it to be used instead", custom.calls)
+	}
+}
@@ -0,0 +1,96 @@
+package baiduocr
+
+import (
+	"crypto/rand"
+	"net"
+	"sync/atomic"
+)
+
+const _DEFAULT_CLIENT_IP = "10.10.10.0"
+
+// reservedNetworks are the IPv4 networks RandomizeClientIP avoids: private,
+// shared, loopback, link-local, documentation, benchmarking, multicast and
+// reserved addresses.
+var reservedNetworks = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8",
+	"169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24", "192.0.2.0/24",
+	"192.88.99.0/24", "192.168.0.0/16", "198.18.0.0/15", "198.51.100.0/24",
+	"203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+)
+
+// Option to set the clientip parameter of the request, which the apistore
+// endpoint expects to be the IP of the end user the image comes from,
+// default is 10.10.10.0. It has no effect on AipOCR.
+func SetClientIP(ip string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.clientIP = func() string { return ip }
+	}}
+}
+
+// Option to send a random public IPv4 address as the clientip parameter of
+// each request, retries included, never one of the private or reserved
+// ranges. Use SetClientIPs to send other addresses. Baidu does not document
+// how clientip counts towards quotas: the quota of the API key applies
+// whatever the address, and only limits Baidu may put on a single client IP
+// can be spread this way. Reporting addresses other than those of your users
+// may break the terms of the service. It has no effect on AipOCR.
+func RandomizeClientIP() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.clientIP = randomClientIP }}
+}
+
+// Option to send the addresses of ips in turn as the clientip parameter of
+// each request, retries included, such as the addresses of the users of a
+// proxy. The turn is shared by all the calls given the same option, such as
+// in OCR.DefaultOptions. The addresses are sent as they are, private ones
+// included. See RandomizeClientIP for the quota implications. It has no
+// effect on AipOCR, or if ips is empty.
+func SetClientIPs(ips []string) BaiduOCROption {
+	ips = append([]string(nil), ips...)
+	next := new(uint32)
+	return BaiduOCROption{func(option *baiduOCROption) {
+		if len(ips) == 0 {
+			return
+		}
+		option.clientIP = func() string {
+			return ips[(atomic.AddUint32(next, 1)-1)%uint32(len(ips))]
+		}
+	}}
+}
+
+func (opts baiduOCROption) nextClientIP() string {
+	if opts.clientIP == nil {
+		return _DEFAULT_CLIENT_IP
+	}
+	return opts.clientIP()
+}
+
+func randomClientIP() string {
+	for {
+		var b [4]byte
+		rand.Read(b[:])
+		ip := net.IP(b[:])
+		if !isReserved(ip) {
+			return ip.String()
+		}
+	}
+}
+
+func isReserved(ip net.IP) bool {
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) (networks []*net.IPNet) {
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package baiduocr_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestClientIP(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.FormValue("clientip")}
+	})
+	clientIP := func(options ...baiduocr.BaiduOCROption) string {
+		results, err := ocr.ParseJPEG(fakeJPEG, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results[0]
+	}
+	if ip := clientIP(); ip != "10.10.10.0" {
+		t.Errorf("got %s by default", ip)
+	}
+	if ip := clientIP(baiduocr.SetClientIP("1.2.3.4")); ip != "1.2.3.4" {
+		t.Errorf("got %s, want 1.2.3.4", ip)
+	}
+
+	pool := baiduocr.SetClientIPs([]string{"1.1.1.1", "192.168.0.1"})
+	var ips []string
+	for i := 0; i < 3; i++ {
+		ips = append(ips, clientIP(pool))
+	}
+	if got := fmt.Sprint(ips); got != "[1.1.1.1 192.168.0.1 1.1.1.1]" {
+		t.Errorf("got %s, want round robin", got)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		ip := net.ParseIP(clientIP(baiduocr.RandomizeClientIP())).To4()
+		if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() {
+			t.Fatalf("got %v, want public IPv4", ip)
+		}
+		seen[ip.String()] = true
+	}
+	if len(seen) < 45 {
+		t.Errorf("got %d different addresses of 50", len(seen))
+	}
+}
@@ -0,0 +1,33 @@
+package baiduocr
+
+import (
+	"context"
+	"time"
+)
+
+// clock tells the time and waits, for the time-dependent features such as
+// retry backoff, retry budgets, access token expiry and cache TTL, so that
+// tests can control time instead of sleeping. Timeouts of requests, timing
+// profiles and keep-alive tickers use the real time regardless.
+type clock struct {
+	now func() time.Time
+	// sleep waits for the duration, returning early with the error of the
+	// context if it is done first.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+var systemClock = clock{now: time.Now, sleep: sleepContext}
+
+// currentClock is the clock in use, only replaced by tests.
+var currentClock = systemClock
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
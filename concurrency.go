@@ -0,0 +1,45 @@
+package baiduocr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var (
+	// slots of OCR.MaxConcurrency, shared by the OCR values with the same
+	// key, as OCR is used by value
+	slotsMutex sync.Mutex
+	slots      = map[slotsKey]chan struct{}{}
+)
+
+type slotsKey struct {
+	apiKey, path string
+	max          int
+}
+
+// acquireSlot waits for a request slot of MaxConcurrency, or for ctx to be
+// done, and returns the function releasing it.
+func (ocr OCR) acquireSlot(ctx context.Context) (release func(), err error) {
+	if ocr.MaxConcurrency <= 0 {
+		return func() {}, nil
+	}
+	key := slotsKey{ocr.APIKey, ocr.APIPath, ocr.MaxConcurrency}
+	slotsMutex.Lock()
+	slot, ok := slots[key]
+	if !ok {
+		slot = make(chan struct{}, ocr.MaxConcurrency)
+		slots[key] = slot
+	}
+	slotsMutex.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a request slot: %w", ctx.Err())
+	}
+}
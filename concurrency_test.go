@@ -0,0 +1,71 @@
+package baiduocr_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return []string{"ok"}
+	})
+	ocr.MaxConcurrency = 2
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		// each goroutine has its own copy of the client
+		go func(ocr baiduocr.OCR) {
+			defer wg.Done()
+			if _, err := ocr.ParseJPEG(fakeJPEG); err != nil {
+				t.Error(err)
+			}
+		}(ocr)
+	}
+	wg.Wait()
+	if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+		t.Errorf("got %d requests in flight at most, want 2", max)
+	}
+}
+
+func TestMaxConcurrencyContext(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		started <- struct{}{}
+		<-release
+		return []string{"ok"}
+	})
+	ocr.MaxConcurrency = 1
+	done := make(chan error, 1)
+	go func() {
+		_, err := ocr.ParseJPEG(fakeJPEG)
+		done <- err
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the deadline of the context waiting for a slot", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
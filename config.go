@@ -0,0 +1,140 @@
+package baiduocr
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"time"
+)
+
+// Config holds the settings of an OCR client, such as loaded from a JSON or
+// YAML file, see NewOCRFromConfig. Zero values mean the defaults.
+type Config struct {
+	// Same as OCR.APIKey, required
+	APIKey string `json:"api_key" yaml:"api_key"`
+	// Same as OCR.APIPath
+	APIPath string `json:"api_path" yaml:"api_path"`
+	// Same as OCR.TimeoutInMilliseconds
+	TimeoutInMilliseconds int64 `json:"timeout_ms" yaml:"timeout_ms"`
+	// Same as OCR.TimeoutMode, "total" (default) or "idle"
+	TimeoutMode string `json:"timeout_mode" yaml:"timeout_mode"`
+	// Same as OCR.MaxRetries
+	MaxRetries int `json:"max_retries" yaml:"max_retries"`
+	// Same as OCR.RetryBackoff, in milliseconds
+	RetryBackoffInMilliseconds int64 `json:"retry_backoff_ms" yaml:"retry_backoff_ms"`
+	// Options of the HTTP client, see WithMaxIdleConns and WithMaxConnsPerHost
+	MaxIdleConns    int `json:"max_idle_conns" yaml:"max_idle_conns"`
+	MaxConnsPerHost int `json:"max_conns_per_host" yaml:"max_conns_per_host"`
+
+	// Same as OCR.DefaultLanguage, one of the languagetype values of Baidu
+	// OCR: CHN_ENG, ENG, JAP, POR, FRE, GER, ITA, SPA, RUS or KOR
+	Language string `json:"language" yaml:"language"`
+	// Same as SetVersion
+	Version string `json:"version" yaml:"version"`
+	// Same as OCR.DefaultPNGBackground, as #rrggbb or #rrggbbaa
+	PNGBackground string `json:"png_background" yaml:"png_background"`
+	// Same as SetNoChromaSubsampling
+	NoChromaSubsampling bool `json:"no_chroma_subsampling" yaml:"no_chroma_subsampling"`
+	// Same as SkipBlankImages, 0 means blank images are not skipped
+	BlankEntropyThreshold float64 `json:"blank_entropy_threshold" yaml:"blank_entropy_threshold"`
+	// Same as SetMedianFilter, 0 means no filter
+	MedianFilterRadius int `json:"median_filter_radius" yaml:"median_filter_radius"`
+	// Same as CleanResults
+	CleanResults bool `json:"clean_results" yaml:"clean_results"`
+	// Same as SetMinBoxArea
+	MinBoxArea int `json:"min_box_area" yaml:"min_box_area"`
+}
+
+// configLanguages are the values of languagetype accepted by Baidu OCR.
+var configLanguages = map[string]bool{
+	_CHINESE: true, _ENGLISH: true, _JAPANESE: true,
+	"POR": true, "FRE": true, "GER": true, "ITA": true, "SPA": true, "RUS": true, "KOR": true,
+}
+
+// Create an OCR client from cfg, like NewOCR with the client options and the
+// fields of cfg, and with the other settings of cfg as OCR.DefaultOptions.
+// An error is returned if cfg is invalid.
+func NewOCRFromConfig(cfg Config) (ocr *OCR, err error) {
+	if cfg.APIKey == "" {
+		err = errors.New("config: api_key must not be empty")
+		return
+	}
+	if cfg.TimeoutInMilliseconds < -1 {
+		err = errors.New("config: timeout_ms must not be less than -1")
+		return
+	}
+	if cfg.MaxRetries < 0 || cfg.RetryBackoffInMilliseconds < 0 || cfg.MaxIdleConns < 0 || cfg.MaxConnsPerHost < 0 ||
+		cfg.BlankEntropyThreshold < 0 || cfg.MedianFilterRadius < 0 || cfg.MinBoxArea < 0 {
+		err = errors.New("config: numbers must not be negative")
+		return
+	}
+	if cfg.Language != "" && !configLanguages[cfg.Language] {
+		err = fmt.Errorf("config: unknown language %q", cfg.Language)
+		return
+	}
+
+	var clientOptions []ClientOption
+	if cfg.MaxIdleConns > 0 {
+		clientOptions = append(clientOptions, WithMaxIdleConns(cfg.MaxIdleConns))
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		clientOptions = append(clientOptions, WithMaxConnsPerHost(cfg.MaxConnsPerHost))
+	}
+	client := NewOCR(cfg.APIKey, clientOptions...)
+	ocr = &client
+	ocr.APIPath = cfg.APIPath
+	ocr.TimeoutInMilliseconds = cfg.TimeoutInMilliseconds
+	switch cfg.TimeoutMode {
+	case "", "total":
+		ocr.TimeoutMode = TimeoutTotal
+	case "idle":
+		ocr.TimeoutMode = TimeoutIdle
+	default:
+		ocr, err = nil, fmt.Errorf("config: unknown timeout_mode %q", cfg.TimeoutMode)
+		return
+	}
+	ocr.MaxRetries = cfg.MaxRetries
+	ocr.RetryBackoff = time.Duration(cfg.RetryBackoffInMilliseconds) * time.Millisecond
+	if cfg.PNGBackground != "" {
+		ocr.DefaultPNGBackground, err = parseHexColor(cfg.PNGBackground)
+		if err != nil {
+			ocr, err = nil, fmt.Errorf("config: png_background: %w", err)
+			return
+		}
+	}
+
+	ocr.DefaultLanguage = cfg.Language
+	if cfg.Version != "" {
+		ocr.DefaultOptions = append(ocr.DefaultOptions, SetVersion(cfg.Version))
+	}
+	if cfg.NoChromaSubsampling {
+		ocr.DefaultOptions = append(ocr.DefaultOptions, SetNoChromaSubsampling())
+	}
+	if cfg.BlankEntropyThreshold > 0 {
+		ocr.DefaultOptions = append(ocr.DefaultOptions, SkipBlankImages(cfg.BlankEntropyThreshold))
+	}
+	if cfg.MedianFilterRadius > 0 {
+		ocr.DefaultOptions = append(ocr.DefaultOptions, SetMedianFilter(cfg.MedianFilterRadius))
+	}
+	if cfg.CleanResults {
+		ocr.DefaultOptions = append(ocr.DefaultOptions, CleanResults())
+	}
+	if cfg.MinBoxArea > 0 {
+		ocr.DefaultOptions = append(ocr.DefaultOptions, SetMinBoxArea(cfg.MinBoxArea))
+	}
+	return
+}
+
+// parseHexColor parses a color written as #rrggbb or #rrggbbaa.
+func parseHexColor(s string) (c color.NRGBA, err error) {
+	c.A = 255
+	switch len(s) {
+	case 7:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("invalid color %q, want #rrggbb or #rrggbbaa", s)
+	}
+	return
+}
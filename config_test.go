@@ -0,0 +1,67 @@
+package baiduocr_test
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestNewOCRFromConfig(t *testing.T) {
+	server, submitted := submittedImage(t)
+	var cfg baiduocr.Config
+	err := json.Unmarshal([]byte(`{
+		"api_key": "test-api-key",
+		"api_path": "`+server.APIPath+`",
+		"timeout_ms": 2000,
+		"timeout_mode": "idle",
+		"language": "ENG",
+		"png_background": "#ffffff",
+		"clean_results": true
+	}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ocr, err := baiduocr.NewOCRFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ocr.TimeoutMode != baiduocr.TimeoutIdle || ocr.TimeoutInMilliseconds != 2000 || ocr.HTTPClient == nil {
+		t.Errorf("got %+v", ocr)
+	}
+	if _, err := ocr.ParseImage(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 8, 8)))); err != nil {
+		t.Fatal(err)
+	}
+	if g := gray(submitted(), 4, 4); g < 250 {
+		t.Errorf("background is %d, want white", g)
+	}
+
+	ocr.APIPath = newTestServer(t, func(r *http.Request) []string {
+		return []string{r.FormValue("languagetype"), " "}
+	}).APIPath
+	results, err := ocr.ParseJPEG(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0] != "ENG" {
+		t.Errorf("got %q, want language and clean results from config", results)
+	}
+	// options of the call override the config
+	if results, _ = ocr.ParseJPEG(fakeJPEG, baiduocr.SetLanguageTypeToJapanese()); results[0] != "JAP" {
+		t.Errorf("got %q", results)
+	}
+
+	for _, invalid := range []baiduocr.Config{
+		{},
+		{APIKey: "key", TimeoutMode: "forever"},
+		{APIKey: "key", PNGBackground: "white"},
+		{APIKey: "key", MedianFilterRadius: -1},
+		{APIKey: "key", Language: "english"},
+	} {
+		if ocr, err := baiduocr.NewOCRFromConfig(invalid); err == nil || ocr != nil {
+			t.Errorf("want error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package baiduocr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ErrContentTypeMismatch is returned when the image is not of the content
+// type of SetExpectedContentType, or with StrictContentType when its content
+// type cannot be told for certain. The returned error wraps it with the
+// detected content type, test for it with errors.Is.
+var ErrContentTypeMismatch = errors.New("image content type mismatch")
+
+// Option to fail with ErrContentTypeMismatch unless the image is of
+// contentType, such as "image/png", as detected by http.DetectContentType,
+// for pipelines that know the format of their images and would rather catch
+// mislabeled files than submit them. The default accepts any supported
+// format.
+func SetExpectedContentType(contentType string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.expectedContentType = contentType }}
+}
+
+// Option to fail with ErrContentTypeMismatch unless the content type of the
+// image is certain: http.DetectContentType only looks at the first bytes of
+// the image, so with this option its header must also be decoded as the
+// detected format, catching corrupt files early rather than sending them to
+// Baidu, including JPEG images submitted unchanged. The default trusts the
+// first bytes.
+func StrictContentType() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.strictContentType = true }}
+}
+
+// checkContentType returns ErrContentTypeMismatch if the image of the detected
+// content type fails SetExpectedContentType or StrictContentType.
+func (opts baiduOCROption) checkContentType(imageBytes []byte, contentType string) error {
+	if opts.expectedContentType != "" && contentType != opts.expectedContentType {
+		return fmt.Errorf("%w: detected %s, want %s", ErrContentTypeMismatch, contentType, opts.expectedContentType)
+	}
+	if !opts.strictContentType {
+		return nil
+	}
+	_, format, err := image.DecodeConfig(bytes.NewReader(imageBytes))
+	if err != nil {
+		return fmt.Errorf("%w: detected %s, but the header is invalid: %v", ErrContentTypeMismatch, contentType, err)
+	}
+	if "image/"+format != contentType {
+		return fmt.Errorf("%w: detected %s, but the header is of %s", ErrContentTypeMismatch, contentType, format)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package baiduocr
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RequestDump is a request as it would be sent to Baidu OCR, returned by
+// DumpRequest to reproduce a call, such as in a report to Baidu support. Its
+// credentials are redacted unless UnredactedDump is used.
+type RequestDump struct {
+	Method string
+	URL    string
+	Header http.Header
+	// Form-urlencoded body, including the base64 image
+	Body string
+}
+
+// Option to keep the credentials in the dump of DumpRequest, the API key,
+// access token or signature, so that it can be replayed as is. Handle such
+// dumps as the credentials themselves.
+func UnredactedDump() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.unredactedDump = true }}
+}
+
+// DumpRequest returns the request ParseImage would send for the image with
+// the options, without sending it. The image is preprocessed and encoded as
+// for ParseImage, and the request is signed if Signer is set. AuditFunc is
+// not called.
+func (ocr OCR) DumpRequest(imageBytes []byte, options ...BaiduOCROption) (dump RequestDump, err error) {
+	opts := ocr.newOptions(options)
+	err = opts.checkImageRequest()
+	if err != nil {
+		return
+	}
+	imageBytes, err = toJPEG(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	ocr.AuditFunc = nil
+	var req *http.Request
+	req, _, err = ocr.newRequest(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	return dumpRequest(req, ocr.Signer, opts, opts.apiKeyHeader)
+}
+
+// DumpRequest returns the request ParseDetailed would send for the image
+// with the endpoint and the options, without sending it. The image is
+// preprocessed and encoded as for ParseDetailed, and the request is signed
+// if Signer is set; otherwise the access token is fetched if not cached, as
+// it is part of the request. AuditFunc is not called.
+func (aip *AipOCR) DumpRequest(endpoint string, imageBytes []byte, options ...BaiduOCROption) (dump RequestDump, err error) {
+	opts := aip.newOptions(options)
+	imageBytes, err = toSubmitted(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	var req *http.Request
+	req, err = aip.newRequest(endpoint, newAipForm(endpoint, imageBytes, opts), opts, nil)
+	if err != nil {
+		return
+	}
+	return dumpRequest(req, aip.Signer, opts)
+}
+
+// dumpRequest returns the dump of req signed by signer, reading its body. The
+// credentials in the URL and in the Authorization header and the headers
+// named are redacted unless UnredactedDump is used.
+func dumpRequest(req *http.Request, signer Signer, opts baiduOCROption, headers ...string) (dump RequestDump, err error) {
+	defer req.Body.Close()
+	if signer != nil {
+		err = signer.Sign(req)
+		if err != nil {
+			return
+		}
+	}
+	var body []byte
+	body, err = ioutil.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+	dump = RequestDump{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: string(body)}
+	dump.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	if opts.unredactedDump {
+		return
+	}
+	dump.URL = redactURL(dump.URL)
+	for _, name := range append(headers, "Authorization") {
+		if dump.Header.Get(name) != "" {
+			dump.Header.Set(name, "REDACTED")
+		}
+	}
+	return
+}
+
+// Curl returns a curl command sending the request, for a shell quoting with
+// single quotes, such as sh or bash.
+func (dump RequestDump) Curl() string {
+	args := []string{"curl", "-X", dump.Method, shellQuote(dump.URL)}
+	var names []string
+	for name := range dump.Header {
+		// curl sets the length of the data itself
+		if name != "Content-Length" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range dump.Header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	args = append(args, "--data-binary", shellQuote(dump.Body))
+	return strings.Join(args, " ")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
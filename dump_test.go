@@ -0,0 +1,84 @@
+package baiduocr_test
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+// replay sends the dumped request and returns the response body.
+func replay(t *testing.T, dump baiduocr.RequestDump) string {
+	req, err := http.NewRequest(dump.Method, dump.URL, strings.NewReader(dump.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = dump.Header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestDumpRequest(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{r.Header.Get("apikey"), r.FormValue("languagetype")}
+	})
+	var audits int
+	ocr.AuditFunc = func(baiduocr.AuditRecord) { audits++ }
+	dump, err := ocr.DumpRequest(fakeJPEG, baiduocr.SetLanguageTypeToEnglish())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.Method != "POST" || dump.URL != ocr.APIPath || dump.Header.Get("apikey") != "REDACTED" || audits != 0 {
+		t.Errorf("got %+v after %d audits", dump, audits)
+	}
+	form, err := url.ParseQuery(dump.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := form.Get("image"); got != base64.StdEncoding.EncodeToString(fakeJPEG) {
+		t.Errorf("image = %s", got)
+	}
+	if curl := dump.Curl(); !strings.HasPrefix(curl, "curl -X POST '"+ocr.APIPath+"' -H ") || !strings.Contains(curl, " -H 'Apikey: REDACTED' ") || !strings.Contains(curl, "--data-binary '") {
+		t.Errorf("curl = %s", curl)
+	}
+
+	dump, err = ocr.DumpRequest(fakeJPEG, baiduocr.SetLanguageTypeToEnglish(), baiduocr.UnredactedDump())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replay(t, dump); !strings.Contains(got, `"test-api-key"`) || !strings.Contains(got, `"ENG"`) {
+		t.Errorf("replayed request got %s", got)
+	}
+}
+
+func TestAipDumpRequest(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult(endpoint, token, r.FormValue("probability"))
+	})
+	dump, err := aip.DumpRequest("general_basic", fakeJPEG, baiduocr.RequestConfidence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(dump.URL, "/ocr/general_basic?access_token=REDACTED") || strings.Contains(dump.Body, "token1") {
+		t.Errorf("got %+v, want the access token redacted", dump)
+	}
+	dump, err = aip.DumpRequest("general_basic", fakeJPEG, baiduocr.RequestConfidence(), baiduocr.UnredactedDump())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replay(t, dump); !strings.Contains(got, `"general_basic"`) || !strings.Contains(got, `"token1"`) || !strings.Contains(got, `"true"`) {
+		t.Errorf("replayed request got %s", got)
+	}
+}
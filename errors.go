@@ -0,0 +1,121 @@
+package baiduocr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OCRError is the error returned when Baidu OCR rejects a request or
+// recognizes no text. Use errors.As to read its fields, and errors.Is with
+// ErrNoText to tell the two cases apart.
+type OCRError struct {
+	// Error code of Baidu, the errNum of the apistore endpoint or the
+	// error_code of AIP endpoints, 0 if no text is recognized
+	Code int
+	// Message of Baidu, or the reason no text is recognized, which may be
+	// empty
+	Message string
+	// Whether the error is that no text is recognized
+	NoText bool
+	// English translation of Message if it is one of the common Chinese
+	// messages of Baidu, empty otherwise, see TranslateErrorMessages
+	Translation string
+
+	format     func(OCRError) string
+	translated bool
+}
+
+// errorTranslations are the English translations of the common Chinese
+// messages of Baidu, worded as its English messages of the same errors.
+var errorTranslations = map[string]string{
+	"参数错误":       "invalid parameter",
+	"未知错误":       "unknown error",
+	"服务暂不可用":     "service temporarily unavailable",
+	"图片为空":       "image is empty",
+	"图片格式错误":     "image format error",
+	"图片大小错误":     "image size error",
+	"识别错误":       "recognize error",
+	"每天请求量超限额":   "open api daily request limit reached",
+	"请求总量超限额":    "open api total request limit reached",
+	"QPS超限额":     "open api qps request limit reached",
+	"无权限访问该用户数据": "no permission to access data",
+	"获取token失败":  "get service token failed",
+}
+
+// Option to format the messages of OCRError in English: a message Baidu
+// returned in Chinese is replaced by its Translation, followed by the
+// original in parentheses, such as for logs read in English:
+//
+//	BaiduOCR error 216201: image format error (图片格式错误)
+//
+// Messages without a translation are kept as they are. Baidu has no setting
+// for the language of its messages, and ignores SetAcceptLanguage. A
+// formatter of SetErrorFormatter takes precedence.
+func TranslateErrorMessages() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.translateErrors = true }}
+}
+
+// Option to set the function formatting the messages of OCRError, such as
+// to translate them for end users. The fields and errors.Is and errors.As
+// keep working the same. The default messages are in English:
+//
+//	BaiduOCR error 216201: image format error
+//	BaiduOCR failed to recognize any text in the image. reason: no digits
+//
+// Errors wrapping an OCRError, such as with the request ID, keep their own
+// text around the formatted message.
+func SetErrorFormatter(format func(OCRError) string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.errorFormatter = format }}
+}
+
+func (err OCRError) Error() string {
+	if err.format != nil {
+		format := err.format
+		err.format = nil
+		return format(err)
+	}
+	message := err.Message
+	if err.translated && err.Translation != "" {
+		message = fmt.Sprintf("%s (%s)", err.Translation, err.Message)
+	}
+	if !err.NoText {
+		return fmt.Sprintf("BaiduOCR error %d: %s", err.Code, message)
+	}
+	if message == "" {
+		return ErrNoText.Error()
+	}
+	return fmt.Sprintf("%s reason: %s", ErrNoText, message)
+}
+
+// Unwrap returns ErrNoText if no text is recognized, so that errors.Is
+// matches it.
+func (err OCRError) Unwrap() error {
+	if err.NoText {
+		return ErrNoText
+	}
+	return nil
+}
+
+// newOCRError returns the OCRError of a message of Baidu, with its
+// translation.
+func newOCRError(code int, message string) OCRError {
+	return OCRError{Code: code, Message: message, Translation: errorTranslations[strings.TrimSpace(message)]}
+}
+
+func noTextError(reason string) error {
+	err := newOCRError(0, reason)
+	err.NoText = true
+	return err
+}
+
+// formatted returns err with the formatter of SetErrorFormatter if it is an
+// OCRError. Wrapped errors are left as they are, as the text of the wrapping
+// error is already made.
+func (opts baiduOCROption) formatted(err error) error {
+	if ocrErr, ok := err.(OCRError); ok && (opts.errorFormatter != nil || opts.translateErrors) {
+		ocrErr.format = opts.errorFormatter
+		ocrErr.translated = opts.translateErrors
+		return ocrErr
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestSetErrorFormatter(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+	chinese := baiduocr.SetErrorFormatter(func(err baiduocr.OCRError) string {
+		if err.NoText {
+			return "未识别到文字"
+		}
+		return fmt.Sprintf("识别失败（%d）", err.Code)
+	})
+
+	response = `{"errNum":300202,"errMsg":"Missing apikey"}`
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	if err == nil || err.Error() != "BaiduOCR error 300202: Missing apikey" {
+		t.Errorf("got %v by default", err)
+	}
+	_, err = ocr.ParseJPEG(fakeJPEG, chinese)
+	var ocrErr baiduocr.OCRError
+	if !errors.As(err, &ocrErr) || ocrErr.Code != 300202 || ocrErr.Message != "Missing apikey" {
+		t.Fatalf("got %#v, want OCRError", err)
+	}
+	if err.Error() != "识别失败（300202）" {
+		t.Errorf("got message %q", err)
+	}
+
+	response = `{"errNum":0,"retData":[]}`
+	for _, err := range []error{
+		func() error { _, err := ocr.ParseJPEG(fakeJPEG, chinese); return err }(),
+		func() error { _, err := ocr.ParseDigits(fakeJPEG, chinese); return err }(),
+	} {
+		if !errors.Is(err, baiduocr.ErrNoText) || err.Error() != "未识别到文字" {
+			t.Errorf("got %v, want formatted ErrNoText", err)
+		}
+	}
+	response = `{"errNum":0,"retData":[{"word":"abc"}]}`
+	if _, err := ocr.ParseDigits(fakeJPEG); !errors.Is(err, baiduocr.ErrNoText) || err.Error() != "BaiduOCR failed to recognize any text in the image. reason: no digits" {
+		t.Errorf("got %v by default", err)
+	}
+}
+
+func TestTranslateErrorMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errNum":300216,"errMsg":"图片格式错误"}`)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	var ocrErr baiduocr.OCRError
+	if !errors.As(err, &ocrErr) || ocrErr.Message != "图片格式错误" || ocrErr.Translation != "image format error" {
+		t.Fatalf("got %#v, want OCRError with the translation", err)
+	}
+	if err.Error() != "BaiduOCR error 300216: 图片格式错误" {
+		t.Errorf("got %q by default", err)
+	}
+	_, err = ocr.ParseJPEG(fakeJPEG, baiduocr.TranslateErrorMessages())
+	if err == nil || err.Error() != "BaiduOCR error 300216: image format error (图片格式错误)" {
+		t.Errorf("got %v, want the translation", err)
+	}
+
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return map[string]interface{}{"error_code": 216201, "error_msg": "image format error"}
+	})
+	_, err = aip.GeneralBasic(fakeJPEG, baiduocr.TranslateErrorMessages())
+	if err == nil || err.Error() != "BaiduOCR error 216201: image format error" {
+		t.Errorf("got %v, want English messages kept", err)
+	}
+}
@@ -0,0 +1,56 @@
+package baiduocr
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// FakeClock is a clock for tests which only moves when told to or slept on,
+// recording the durations slept.
+type FakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+// UseFakeClock makes the package use a fake clock starting at start until
+// the end of the test.
+func UseFakeClock(t testing.TB, start time.Time) *FakeClock {
+	fake := &FakeClock{now: start}
+	currentClock = clock{now: fake.Now, sleep: fake.sleep}
+	t.Cleanup(func() { currentClock = systemClock })
+	return fake
+}
+
+func (fake *FakeClock) Now() time.Time {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.now
+}
+
+// Advance moves the clock forward by d.
+func (fake *FakeClock) Advance(d time.Duration) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.now = fake.now.Add(d)
+}
+
+// Sleeps returns the durations slept so far.
+func (fake *FakeClock) Sleeps() []time.Duration {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return append([]time.Duration(nil), fake.sleeps...)
+}
+
+func (fake *FakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.sleeps = append(fake.sleeps, d)
+	fake.now = fake.now.Add(d)
+	return nil
+}
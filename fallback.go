@@ -0,0 +1,46 @@
+package baiduocr
+
+import (
+	"errors"
+	"image"
+)
+
+// PreprocessFunc is a preprocessing step of the decoded image, returning the
+// image to submit instead, see SetFallbackPreprocessing.
+type PreprocessFunc func(image.Image) (image.Image, error)
+
+// Option to submit the image again with each of fallbacks in turn while
+// Baidu recognizes no text in it, such as with inverted colors or a higher
+// contrast for stubborn captchas. Each fallback is applied to the image
+// after the other preprocessing options, and the first results are returned,
+// with the number of the fallback which gave them, from 1, in
+// FallbackPreprocessing of ResultMeta. Other errors, such as of the API key
+// or quota, are returned at once, and no fallback is tried once the context
+// of SetContext is done. If all fallbacks fail, the error of the last one is
+// returned.
+func SetFallbackPreprocessing(fallbacks []PreprocessFunc) BaiduOCROption {
+	fallbacks = append([]PreprocessFunc(nil), fallbacks...)
+	return BaiduOCROption{func(option *baiduOCROption) { option.fallbackPreprocessing = fallbacks }}
+}
+
+// withFallbackPreprocessing calls parse with opts, then with each fallback of
+// SetFallbackPreprocessing added to the transforms of opts while the text is
+// not found.
+func (opts baiduOCROption) withFallbackPreprocessing(parse func(baiduOCROption) ([]Word, ResultMeta, error)) (words []Word, meta ResultMeta, err error) {
+	words, meta, err = parse(opts)
+	for i, fallback := range opts.fallbackPreprocessing {
+		if !errors.Is(err, ErrNoText) || opts.context != nil && opts.context.Err() != nil {
+			return
+		}
+		fallback := fallback
+		fallbackOpts := opts
+		fallbackOpts.transforms = append(opts.transforms[:len(opts.transforms):len(opts.transforms)], func(img image.Image, _ baiduOCROption) (image.Image, error) {
+			return fallback(img)
+		})
+		words, meta, err = parse(fallbackOpts)
+		if err == nil {
+			meta.FallbackPreprocessing = i + 1
+		}
+	}
+	return
+}
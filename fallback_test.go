@@ -0,0 +1,63 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func invert(img image.Image) (image.Image, error) {
+	bounds := img.Bounds()
+	inverted := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			inverted.SetGray(x, y, color.Gray{255 - gray(img, x, y)})
+		}
+	}
+	return inverted, nil
+}
+
+func TestSetFallbackPreprocessing(t *testing.T) {
+	var requests int32
+	// text is only found in dark images
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		atomic.AddInt32(&requests, 1)
+		if img := requestImage(t, r); img != nil && gray(img, 5, 5) < 128 {
+			return []string{"ok"}
+		}
+		return nil
+	})
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(src, src.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	png := encodePNG(t, src)
+	same := func(img image.Image) (image.Image, error) { return img, nil }
+
+	words, meta, err := ocr.ParseDetailed(png, baiduocr.SetFallbackPreprocessing([]baiduocr.PreprocessFunc{same, invert, same}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || meta.FallbackPreprocessing != 2 || requests != 3 {
+		t.Errorf("got %d words from fallback %d after %d requests, want 1 from fallback 2 after 3", len(words), meta.FallbackPreprocessing, requests)
+	}
+
+	requests = 0
+	_, _, err = ocr.ParseDetailed(png, baiduocr.SetFallbackPreprocessing([]baiduocr.PreprocessFunc{same, same}))
+	if !errors.Is(err, baiduocr.ErrNoText) || requests != 3 {
+		t.Errorf("got %v after %d requests, want ErrNoText after 3", err, requests)
+	}
+
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		atomic.AddInt32(&requests, 1)
+		return map[string]interface{}{"error_code": 17, "error_msg": "Open api daily request limit reached"}
+	})
+	requests = 0
+	if _, _, err = aip.ParseDetailed("general_basic", png, baiduocr.SetFallbackPreprocessing([]baiduocr.PreprocessFunc{invert})); err == nil || requests != 1 {
+		t.Errorf("got %v after %d requests, want the quota error at once", err, requests)
+	}
+}
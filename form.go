@@ -0,0 +1,165 @@
+package baiduocr
+
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type (
+	// imageForm is a form-urlencoded request body made of params and an
+	// image field. The base64 encoding of the image is streamed into the body
+	// instead of being held in memory as a whole. If image is nil, the image
+	// field is a URL in params. Fields are in the order of SetFormFieldOrder.
+	imageForm struct {
+		params   url.Values
+		image    []byte
+		encoding *base64.Encoding
+		order    []string
+	}
+
+	// queryEscaper writes query-escaped bytes to w, or only counts them if w
+	// is nil.
+	queryEscaper struct {
+		w     io.Writer
+		count int64
+	}
+)
+
+func (opts baiduOCROption) newImageForm(params url.Values, imageBytes []byte) imageForm {
+	if opts.imageType == ImageTypeURL {
+		params.Set("image", string(imageBytes))
+		return imageForm{params: params, order: opts.formFieldOrder}
+	}
+	encoding := base64.StdEncoding
+	if opts.urlSafeBase64 {
+		encoding = base64.URLEncoding
+	}
+	return imageForm{params, imageBytes, encoding, opts.formFieldOrder}
+}
+
+// newRequest returns a POST request of the form to path.
+func (form imageForm) newRequest(path string) (req *http.Request, err error) {
+	body, length := form.body()
+	req, err = http.NewRequest("POST", path, body)
+	if err != nil {
+		body.Close()
+		return
+	}
+	req.ContentLength = length
+	req.GetBody = func() (io.ReadCloser, error) {
+		body, _ := form.body()
+		return body, nil
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	return
+}
+
+// warnLargePayload logs a warning with logf if the length of a request body
+// to path is above threshold, see OCR.PayloadWarnBytes.
+func warnLargePayload(logf func(string, ...interface{}), threshold int64, path string, length int64) {
+	if logf == nil || threshold <= 0 || length <= threshold {
+		return
+	}
+	logf("baiduocr: request body to %s is %d bytes, above the warning threshold of %d bytes", path, length, threshold)
+}
+
+// body returns a reader of the form and its length. The form is written to
+// the reader by a goroutine, which exits when the reader is read to the end
+// or closed.
+func (form imageForm) body() (body io.ReadCloser, length int64) {
+	prefix, suffix := form.fields()
+	if form.image == nil {
+		return ioutil.NopCloser(strings.NewReader(prefix)), int64(len(prefix))
+	}
+	if prefix != "" {
+		prefix += "&"
+	}
+	prefix += "image="
+	if suffix != "" {
+		suffix = "&" + suffix
+	}
+	// the length of base64 follows from the length of the image, but
+	// escaping triples +, / and =, so the image is encoded once to count them
+	counter := &queryEscaper{}
+	form.writeImage(counter)
+	length = int64(len(prefix)) + counter.count + int64(len(suffix))
+
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := io.WriteString(writer, prefix)
+		if err == nil {
+			err = form.writeImage(&queryEscaper{w: writer})
+		}
+		if err == nil {
+			_, err = io.WriteString(writer, suffix)
+		}
+		writer.CloseWithError(err)
+	}()
+	body = reader
+	return
+}
+
+// fields returns the encoded params before and after the image field. Without
+// an order, they are sorted as by url.Values.Encode, all before the image.
+func (form imageForm) fields() (before, after string) {
+	if form.order == nil {
+		return form.params.Encode(), ""
+	}
+	var keys []string
+	listed := map[string]bool{}
+	for _, key := range form.order {
+		if !listed[key] && (key == "image" && form.image != nil || form.params[key] != nil) {
+			keys = append(keys, key)
+		}
+		listed[key] = true
+	}
+	var rest []string
+	for key := range form.params {
+		if !listed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	var encoded [2][]string
+	part := 0
+	for _, key := range keys {
+		if key == "image" && form.image != nil {
+			part = 1
+			continue
+		}
+		for _, value := range form.params[key] {
+			encoded[part] = append(encoded[part], url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(encoded[0], "&"), strings.Join(encoded[1], "&")
+}
+
+func (form imageForm) writeImage(escaper *queryEscaper) error {
+	encoder := base64.NewEncoder(form.encoding, escaper)
+	if _, err := encoder.Write(form.image); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// encodedImageLength returns the length of the base64 encoding of the image.
+func (form imageForm) encodedImageLength() int {
+	return form.encoding.EncodedLen(len(form.image))
+}
+
+func (escaper *queryEscaper) Write(p []byte) (n int, err error) {
+	escaped := url.QueryEscape(string(p))
+	escaper.count += int64(len(escaped))
+	if escaper.w != nil {
+		_, err = io.WriteString(escaper.w, escaped)
+	}
+	n = len(p)
+	return
+}
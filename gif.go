@@ -0,0 +1,62 @@
+package baiduocr
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// Option to choose the frame of an animated GIF image to recognize, default
+// is 0, the first frame. The frame is rendered as it is displayed, that is
+// composited over the frames before it. An index past the last frame selects
+// the last frame, a negative index the first one. Still WebP images are
+// decoded when built with the webp build tag, which pulls in
+// golang.org/x/image/webp; animated WebP images are not supported, as that
+// package decodes no animation, so the index does not apply to WebP.
+func SetFrameIndex(i int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.frameIndex = i }}
+}
+
+// decodeGIFFrame decodes the frame at index of the GIF image, clamped to the
+// frames of the image.
+func decodeGIFFrame(reader io.Reader, index int) (image.Image, error) {
+	g, err := gif.DecodeAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if index >= len(g.Image) {
+		index = len(g.Image) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	for i := 0; i <= index; i++ {
+		frame := g.Image[i]
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious && i < index {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if i == index {
+			break
+		}
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return canvas, nil
+}
@@ -0,0 +1,73 @@
+package baiduocr_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+var gifPalette = color.Palette{color.White, color.Black, color.RGBA{255, 0, 0, 255}}
+
+func encodeGIF(t *testing.T, frames ...*image.Paletted) []byte {
+	var buf bytes.Buffer
+	g := &gif.GIF{Image: frames, Delay: make([]int, len(frames))}
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func solidFrame(rect image.Rectangle, index uint8) *image.Paletted {
+	frame := image.NewPaletted(rect, gifPalette)
+	for i := range frame.Pix {
+		frame.Pix[i] = index
+	}
+	return frame
+}
+
+func TestSetFrameIndex(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	single := encodeGIF(t, solidFrame(image.Rect(0, 0, 32, 32), 1))
+	if _, err := ocr.ParseImage(single, baiduocr.SetFrameIndex(3)); err != nil {
+		t.Fatal(err)
+	}
+	if g := gray(submitted(), 16, 16); g > 50 {
+		t.Errorf("single frame is %d, want black", g)
+	}
+
+	full := image.Rect(0, 0, 32, 32)
+	multi := encodeGIF(t,
+		solidFrame(full, 0),
+		solidFrame(image.Rect(0, 0, 16, 32), 1),  // left half black
+		solidFrame(image.Rect(16, 0, 32, 32), 2), // right half red
+	)
+	for _, test := range []struct {
+		index       int
+		left, right uint8
+	}{
+		{0, 255, 255},
+		{1, 0, 255},
+		{2, 0, 76},
+		{9, 0, 76},
+		{-1, 255, 255},
+	} {
+		if _, err := ocr.ParseImage(multi, baiduocr.SetFrameIndex(test.index)); err != nil {
+			t.Fatal(err)
+		}
+		img := submitted()
+		if left, right := gray(img, 8, 16), gray(img, 24, 16); absDiff(left, test.left) > 10 || absDiff(right, test.right) > 10 {
+			t.Errorf("frame %d: got %d, %d, want %d, %d", test.index, left, right, test.left, test.right)
+		}
+	}
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
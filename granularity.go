@@ -0,0 +1,43 @@
+package baiduocr
+
+import "unicode"
+
+const (
+	_GRANULARITY_BIG   = "big"
+	_GRANULARITY_SMALL = "small"
+
+	// a word this long and this many times as wide as high is taken for
+	// characters merged by AutoGranularity
+	_MERGED_MIN_CHARS    = 4
+	_MERGED_ASPECT_RATIO = 2
+)
+
+// Option to let AipOCR choose the granularity of the recognition, for
+// captchas whose characters Baidu may read as one word: the image is
+// recognized by words first, and recognized again by characters, as with
+// RequestChars, if the words look like merged characters, that is if there is
+// exactly one word, with a bounding box at least 2 times as wide as high and
+// at least 4 characters besides spaces. The granularity used is returned in
+// Granularity of ResultMeta. It only works with the endpoints accepting
+// recognize_granularity (general and accurate), and has no effect with
+// RequestChars or on OCR.
+func AutoGranularity() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.autoGranularity = true }}
+}
+
+// mergedChars reports whether words look like characters recognized as one
+// word, as described in AutoGranularity.
+func mergedChars(words []Word) bool {
+	if len(words) != 1 {
+		return false
+	}
+	word := words[0]
+	chars := 0
+	for _, r := range word.Text {
+		if !unicode.IsSpace(r) {
+			chars++
+		}
+	}
+	return chars >= _MERGED_MIN_CHARS && !word.Rect.Empty() &&
+		word.Rect.Dx() >= _MERGED_ASPECT_RATIO*word.Rect.Dy()
+}
@@ -0,0 +1,50 @@
+package baiduocr
+
+import (
+	"image"
+)
+
+// Heatmap is a coarse grid of confidence values over an image, to spot the
+// regions Baidu was unsure about.
+type Heatmap struct {
+	Rows, Cols int
+	// Size of the image the grid covers
+	ImageSize image.Point
+	// Values of the cells in row-major order. A cell has the lowest
+	// confidence of the words overlapping it, or -1 if there is no word with
+	// a confidence there.
+	Values []float64
+}
+
+// At returns the value of the cell at row and col.
+func (heatmap *Heatmap) At(row, col int) float64 {
+	return heatmap.Values[row*heatmap.Cols+col]
+}
+
+// newHeatmap returns the heatmap of the words over an image of the size, or
+// nil if no located word has a confidence.
+func newHeatmap(words []Word, size image.Point, rows, cols int) *Heatmap {
+	if rows < 1 || cols < 1 || size.X < 1 || size.Y < 1 {
+		return nil
+	}
+	heatmap := &Heatmap{Rows: rows, Cols: cols, ImageSize: size, Values: make([]float64, rows*cols)}
+	for i := range heatmap.Values {
+		heatmap.Values[i] = -1
+	}
+	found := false
+	for i, cell := range gridCells(image.Rectangle{Max: size}, rows, cols) {
+		for _, word := range words {
+			if !word.HasConfidence || !word.Rect.Overlaps(cell) {
+				continue
+			}
+			found = true
+			if v := &heatmap.Values[i]; *v < 0 || word.Confidence < *v {
+				*v = word.Confidence
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return heatmap
+}
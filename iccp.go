@@ -0,0 +1,39 @@
+package baiduocr
+
+import (
+	"bytes"
+	"strings"
+)
+
+// pngICCProfile returns the name of the ICC profile embedded in the iCCP
+// chunk of a PNG image, or false if it has none.
+func pngICCProfile(imageBytes []byte) (name string, ok bool) {
+	data, found := pngChunk(imageBytes, "iCCP")
+	if !found {
+		return
+	}
+	// the name is followed by a null separator, the compression method and
+	// the compressed profile
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+	return string(data), true
+}
+
+// isSRGBProfile reports whether the name of an ICC profile is one of sRGB,
+// such as "sRGB IEC61966-2.1". Only the name is compared: the profile itself
+// is not parsed.
+func isSRGBProfile(name string) bool {
+	return strings.Contains(strings.ToLower(name), "srgb")
+}
+
+// warnICCProfile logs a warning with logf if the image is a PNG image with an
+// ICC profile other than sRGB, whose colors are decoded as if they were sRGB.
+func warnICCProfile(logf func(string, ...interface{}), imageBytes []byte) {
+	if logf == nil {
+		return
+	}
+	if name, ok := pngICCProfile(imageBytes); ok && !isSRGBProfile(name) {
+		logf("baiduocr: PNG image has ICC profile %q, whose colors are decoded as sRGB and may be shifted", name)
+	}
+}
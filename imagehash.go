@@ -0,0 +1,27 @@
+package baiduocr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Option to compute the SHA-256 of the image exactly as submitted to Baidu,
+// that is after preprocessing and encoding to JPEG (or PNG with SmartFormat),
+// not of the image given, such as to correlate results with the images of
+// other systems and to deduplicate them. It is returned in hex in ImageSHA256
+// of ResultMeta, also for cached results, and of AuditRecord. The cache key
+// of OCR.Cache is a different hash, as it covers the options as well. It is
+// off by default to spare hashing large images.
+func ComputeImageHash() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.computeImageHash = true }}
+}
+
+// imageHash returns the hex SHA-256 of the submitted image, or an empty
+// string without ComputeImageHash.
+func (opts baiduOCROption) imageHash(imageBytes []byte) string {
+	if !opts.computeImageHash {
+		return ""
+	}
+	sum := sha256.Sum256(imageBytes)
+	return hex.EncodeToString(sum[:])
+}
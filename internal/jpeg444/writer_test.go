@@ -0,0 +1,89 @@
+package jpeg444
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func colorfulText() *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, 123, 45))
+	for y := 0; y < 45; y++ {
+		for x := 0; x < 123; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			// thin red and blue strokes, one or two pixels wide
+			if x%7 == 0 || (x%11 < 2 && y%9 < 6) {
+				c = color.RGBA{220, 0, 0, 255}
+			} else if y%5 == 0 {
+				c = color.RGBA{0, 0, 200, 255}
+			}
+			m.Set(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestEncodeIsDecodableWithoutSubsampling(t *testing.T) {
+	src := colorfulText()
+	var buf bytes.Buffer
+	if err := Encode(&buf, src, &Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != src.Bounds() {
+		t.Fatalf("bounds = %v, want %v", img.Bounds(), src.Bounds())
+	}
+	ycbcr, ok := img.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("decoded %T, want *image.YCbCr", img)
+	}
+	if ycbcr.SubsampleRatio != image.YCbCrSubsampleRatio444 {
+		t.Errorf("subsample ratio = %v, want 4:4:4", ycbcr.SubsampleRatio)
+	}
+}
+
+func TestEncodeKeepsChromaSharper(t *testing.T) {
+	src := colorfulText()
+	var full, sub bytes.Buffer
+	if err := Encode(&full, src, &Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&sub, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	fullErr, subErr := diff(t, src, full.Bytes()), diff(t, src, sub.Bytes())
+	t.Logf("4:4:4 %d bytes, error %d; 4:2:0 %d bytes, error %d", full.Len(), fullErr, sub.Len(), subErr)
+	if fullErr >= subErr {
+		t.Errorf("4:4:4 error %d is not lower than 4:2:0 error %d", fullErr, subErr)
+	}
+}
+
+// diff returns the sum of absolute channel differences between src and the
+// decoded JPEG data.
+func diff(t *testing.T, src image.Image, data []byte) (sum int) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r0, g0, b0, _ := src.At(x, y).RGBA()
+			r1, g1, b1, _ := img.At(x, y).RGBA()
+			sum += abs(int(r0>>8)-int(r1>>8)) + abs(int(g0>>8)-int(g1>>8)) + abs(int(b0>>8)-int(b1>>8))
+		}
+	}
+	return
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,212 @@
+package baiduocr
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Option to set the width in pixels of a space in the text of ParseLayout,
+// that is how many pixels of horizontal gap between words make one space.
+// The default is the median width of a character of the words.
+func SetLayoutSpaceWidth(pixels float64) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.layoutSpaceWidth = pixels }}
+}
+
+// Option to set the height in pixels of a line in the text of ParseLayout,
+// that is how many pixels of vertical gap between lines make one empty line.
+// The default is the median height of the words.
+func SetLayoutLineHeight(pixels float64) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.layoutLineHeight = pixels }}
+}
+
+// Read text from JPEG/PNG image and lay it out as in the image, for
+// monospace display of receipts and tables: lines are grouped as in
+// ParseLines and separated by newlines, with empty lines for large vertical
+// gaps, and words are indented by spaces according to their horizontal
+// positions, see SetLayoutSpaceWidth and SetLayoutLineHeight. Words are
+// separated by a space at least. Wide characters such as Chinese count as
+// one column, so mixed scripts only line up approximately.
+func (ocr OCR) ParseLayout(imageBytes []byte, options ...BaiduOCROption) (text string, err error) {
+	var words []Word
+	words, _, err = ocr.ParseDetailed(imageBytes, options...)
+	if err != nil {
+		return
+	}
+	text = layoutText(words, ocr.newOptions(options))
+	return
+}
+
+// Same as OCR.ParseLayout, with the named endpoint, which must locate text,
+// like general or accurate. With other endpoints words are only separated
+// by newlines.
+func (aip *AipOCR) ParseLayout(endpoint string, imageBytes []byte, options ...BaiduOCROption) (text string, err error) {
+	var words []Word
+	words, _, err = aip.ParseDetailed(endpoint, imageBytes, options...)
+	if err != nil {
+		return
+	}
+	text = layoutText(words, newOptions(options))
+	return
+}
+
+// lineTexts groups the words into lines of text, as laid out by Baidu if
+// asked to and available, or else by position.
+func lineTexts(words []Word, meta ResultMeta, opts baiduOCROption) (lines [][]string) {
+	var groups [][]Word
+	if opts.baiduLines && meta.Lines != nil {
+		groups = baiduLines(words, meta.Lines)
+	} else {
+		groups = groupLines(words)
+	}
+	for _, group := range groups {
+		lines = append(lines, wordTexts(group))
+	}
+	return
+}
+
+// baiduLines returns the words grouped by the indexes of lines, skipping
+// indexes out of range.
+func baiduLines(words []Word, lines [][]int) (groups [][]Word) {
+	for _, line := range lines {
+		var group []Word
+		for _, i := range line {
+			if i >= 0 && i < len(words) {
+				group = append(group, words[i])
+			}
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return
+}
+
+// groupLines groups words by position. Words are taken from top to bottom,
+// and a word joins the current line if its vertical center is within the
+// vertical extent of the line, else it starts a new line. Words of a line are
+// sorted from left to right. Words without a bounding box come last, each
+// in a line by itself, in the order they were returned.
+func groupLines(words []Word) (groups [][]Word) {
+	var located, unlocated []Word
+	for _, word := range words {
+		if word.Rect.Empty() {
+			unlocated = append(unlocated, word)
+		} else {
+			located = append(located, word)
+		}
+	}
+	sort.SliceStable(located, func(i, j int) bool {
+		return located[i].Rect.Min.Y+located[i].Rect.Max.Y < located[j].Rect.Min.Y+located[j].Rect.Max.Y
+	})
+	var minY, maxY int
+	for _, word := range located {
+		rect := word.Rect
+		center := (rect.Min.Y + rect.Max.Y) / 2
+		last := len(groups) - 1
+		if last < 0 || center < minY || center > maxY {
+			groups = append(groups, []Word{word})
+			minY, maxY = rect.Min.Y, rect.Max.Y
+			continue
+		}
+		groups[last] = append(groups[last], word)
+		if rect.Min.Y < minY {
+			minY = rect.Min.Y
+		}
+		if rect.Max.Y > maxY {
+			maxY = rect.Max.Y
+		}
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Rect.Min.X < group[j].Rect.Min.X })
+	}
+	for _, word := range unlocated {
+		groups = append(groups, []Word{word})
+	}
+	return
+}
+
+// layoutText lays out the words in lines by their positions.
+func layoutText(words []Word, opts baiduOCROption) string {
+	spaceWidth, lineHeight := opts.layoutSpaceWidth, opts.layoutLineHeight
+	var heights []float64
+	minX := math.MaxInt32
+	for _, word := range words {
+		if word.Rect.Empty() {
+			continue
+		}
+		// words without text are indented too, so they count for the margin
+		if word.Rect.Min.X < minX {
+			minX = word.Rect.Min.X
+		}
+		if word.Text != "" {
+			heights = append(heights, float64(word.Rect.Dy()))
+		}
+	}
+	if spaceWidth <= 0 {
+		spaceWidth = medianCharWidth(words)
+	}
+	if lineHeight <= 0 {
+		lineHeight = median(heights)
+	}
+
+	var b strings.Builder
+	var previous image.Rectangle
+	for i, group := range groupLines(words) {
+		var bounds image.Rectangle
+		for _, word := range group {
+			bounds = bounds.Union(word.Rect)
+		}
+		if i > 0 {
+			b.WriteString("\n")
+			// lines overlap if a word starts a line by its center
+			// while its top is within the previous one
+			if gap := float64(bounds.Min.Y - previous.Max.Y); !bounds.Empty() && !previous.Empty() && lineHeight > 0 && gap > 0 {
+				b.WriteString(strings.Repeat("\n", int(gap/lineHeight)))
+			}
+		}
+		previous = bounds
+		column := 0
+		for j, word := range group {
+			start := 0
+			if !word.Rect.Empty() && spaceWidth > 0 {
+				start = int(math.Round(float64(word.Rect.Min.X-minX) / spaceWidth))
+			}
+			if j > 0 && start <= column {
+				start = column + 1
+			}
+			b.WriteString(strings.Repeat(" ", start-column))
+			b.WriteString(word.Text)
+			column = start + utf8.RuneCountInString(word.Text)
+		}
+	}
+	return b.String()
+}
+
+// medianCharWidth returns the median width of a character of the words with
+// a bounding box, or 0 if there are none.
+func medianCharWidth(words []Word) float64 {
+	var widths []float64
+	for _, word := range words {
+		if n := utf8.RuneCountInString(word.Text); n > 0 && !word.Rect.Empty() {
+			widths = append(widths, float64(word.Rect.Dx())/float64(n))
+		}
+	}
+	return median(widths)
+}
+
+// median returns the median of values, or 0 if there are none.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
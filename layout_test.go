@@ -0,0 +1,149 @@
+package baiduocr_test
+
+import (
+	"fmt"
+	"image"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseLines(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{
+			{Text: "world", Rect: image.Rect(60, 12, 110, 30)},
+			{Text: "second", Rect: image.Rect(10, 50, 70, 70)},
+			{Text: "hello", Rect: image.Rect(10, 10, 55, 28)},
+			{Text: "line", Rect: image.Rect(80, 48, 120, 72)},
+		}
+	})
+	lines, err := ocr.ParseLines(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(lines), "[[hello world] [second line]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// OCR endpoint never groups words, the option falls back to positions
+	if lines, err = ocr.ParseLines(fakeJPEG, baiduocr.GroupByBaiduLines()); err != nil || len(lines) != 2 {
+		t.Errorf("got %v, %v", lines, err)
+	}
+}
+
+func TestAipParseLinesByBaidu(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		ret := wordsResult("a", "b", "c").(map[string]interface{})
+		if r.FormValue("paragraph") == "true" {
+			ret["paragraphs_result"] = []map[string][]int{{"words_result_idx": {0, 2}}, {"words_result_idx": {1}}}
+		}
+		return ret
+	})
+	lines, err := aip.ParseLines("general_basic", fakeJPEG, baiduocr.GroupByBaiduLines())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(lines), "[[a c] [b]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if lines, _ = aip.ParseLines("general_basic", fakeJPEG); fmt.Sprint(lines) != "[[a] [b] [c]]" {
+		t.Errorf("got %v without boxes or grouping", lines)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{
+			{Text: "3.50", Rect: image.Rect(150, 10, 190, 30)},
+			{Text: "Coffee", Rect: image.Rect(10, 10, 70, 30)},
+			{Text: "Tea", Rect: image.Rect(10, 40, 40, 60)},
+			{Text: "2.00", Rect: image.Rect(150, 40, 190, 60)},
+			{Text: "Total", Rect: image.Rect(10, 100, 60, 120)},
+			{Text: "5.50", Rect: image.Rect(150, 100, 190, 120)},
+		}
+	})
+	text, err := ocr.ParseLayout(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Coffee        3.50\n" +
+		"Tea           2.00\n" +
+		"\n" +
+		"\n" +
+		"Total         5.50"
+	if text != want {
+		t.Errorf("got\n%s\nwant\n%s", text, want)
+	}
+	// wide spaces and tall lines squeeze the layout, but words stay apart
+	text, err = ocr.ParseLayout(fakeJPEG, baiduocr.SetLayoutSpaceWidth(100), baiduocr.SetLayoutLineHeight(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Coffee 3.50\nTea 2.00\nTotal 5.50"; text != want {
+		t.Errorf("got\n%s\nwant\n%s", text, want)
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{
+			{Text: "Quarterly sales", Rect: image.Rect(10, 10, 250, 30)},
+			{Text: "Item", Rect: image.Rect(10, 40, 50, 60)},
+			{Text: "Qty", Rect: image.Rect(120, 40, 150, 60)},
+			{Text: "Price", Rect: image.Rect(200, 40, 250, 60)},
+			{Text: "Iced", Rect: image.Rect(10, 70, 50, 90)},
+			{Text: "tea", Rect: image.Rect(58, 70, 88, 90)},
+			{Text: "2", Rect: image.Rect(140, 70, 150, 90)},
+			{Text: "12.50", Rect: image.Rect(200, 70, 250, 90)},
+			{Text: "Coffee", Rect: image.Rect(10, 100, 70, 120)},
+			{Text: "3.50", Rect: image.Rect(210, 100, 250, 120)},
+			{Text: "note"},
+		}
+	})
+	table, err := ocr.ParseTable(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[["Quarterly sales" "" ""] ["Item" "Qty" "Price"] ["Iced tea" "2" "12.50"] ["Coffee" "" "3.50"] ["note" "" ""]]`
+	if got := fmt.Sprintf("%q", table); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// a wide tolerance joins the words of a row into one cell
+	if table, err = ocr.ParseTable(fakeJPEG, baiduocr.SetLayoutSpaceWidth(100)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprintf("%q", table[1]), `["Item Qty Price" ""]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseLayoutMargins(t *testing.T) {
+	var words []baiduocr.Word
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word { return words })
+	// a word without text left of the others sets the margin
+	words = []baiduocr.Word{
+		{Text: "hello", Rect: image.Rect(100, 0, 150, 10)},
+		{Text: "", Rect: image.Rect(0, 0, 20, 10)},
+	}
+	text, err := ocr.ParseLayout(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "          hello"; text != want {
+		t.Errorf("got %q, want %q", text, want)
+	}
+	// lines overlapping vertically are not separated by empty lines
+	words = []baiduocr.Word{
+		{Text: "a", Rect: image.Rect(0, 0, 10, 100)},
+		{Text: "b", Rect: image.Rect(0, 20, 10, 190)},
+		{Text: "c", Rect: image.Rect(0, 200, 10, 205)},
+		{Text: "d", Rect: image.Rect(0, 210, 10, 215)},
+		{Text: "e", Rect: image.Rect(0, 220, 10, 225)},
+	}
+	if text, err = ocr.ParseLayout(fakeJPEG, baiduocr.SetLayoutLineHeight(5)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\nb\n\n\nc\n\nd\n\ne"; text != want {
+		t.Errorf("got %q, want %q", text, want)
+	}
+}
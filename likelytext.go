@@ -0,0 +1,141 @@
+package baiduocr
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// minimum difference of the mean gray levels of ink and background
+	_TEXT_MIN_CONTRAST = 32
+	// minimum height in pixels of a character-like component
+	_TEXT_MIN_CHAR_HEIGHT = 4
+)
+
+// Predict locally, without calling Baidu OCR, whether the image likely has
+// text, to save the calls for images that do not. This is a heuristic, not a
+// guarantee: it can miss faint or tiny text and take drawings for text.
+//
+// The image is decoded and preprocessed as the Parse methods would with the
+// options, converted to grayscale, and binarized at the threshold of Otsu's
+// method; the side with fewer pixels is the ink. Confidence is 0 if the mean
+// gray levels of ink and background differ by less than 32, as in blank
+// images. Otherwise the ink pixels touching horizontally or vertically form
+// components, and a component is character-like if its bounding box is at
+// least 4 pixels tall, at most 90% as tall and as wide as the image, and 10%
+// to 90% filled with ink. With c character-like components, confidence is
+// c/(c+2) times the share of the ink pixels in them: it grows with the
+// number of characters, or strokes of Chinese characters, and shrinks with
+// the ink of specks, lines and blobs. The image likely has text if
+// confidence is at least 0.5, so at least two characters are needed.
+//
+// It takes time proportional to the number of pixels. SkipBlankImages is a
+// cheaper check of blank images only.
+func LikelyHasText(imageBytes []byte, options ...BaiduOCROption) (likely bool, confidence float64, err error) {
+	var img image.Image
+	img, err = normalizedImage(imageBytes, newOptions(options))
+	if err != nil {
+		return
+	}
+	confidence = textConfidence(img)
+	likely = confidence >= 0.5
+	return
+}
+
+func textConfidence(img image.Image) float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < 1 || h < 1 {
+		return 0
+	}
+	gray := make([]uint8, w*h)
+	var histogram [256]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			g := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			gray[y*w+x] = g
+			histogram[g]++
+		}
+	}
+	threshold, dark, light := otsu(histogram, w*h)
+	if light-dark < _TEXT_MIN_CONTRAST {
+		return 0
+	}
+	var darkCount int
+	for g := 0; g <= threshold; g++ {
+		darkCount += histogram[g]
+	}
+	inkIsDark := darkCount*2 <= w*h
+	ink := make([]bool, w*h)
+	for i, g := range gray {
+		ink[i] = (int(g) <= threshold) == inkIsDark
+	}
+
+	chars, charPixels, inkPixels := 0, 0, 0
+	visited := make([]bool, w*h)
+	var stack []int
+	for start := range ink {
+		if !ink[start] || visited[start] {
+			continue
+		}
+		visited[start] = true
+		stack = append(stack[:0], start)
+		minX, minY, maxX, maxY, count := w, h, -1, -1, 0
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			count++
+			minX, minY, maxX, maxY = min(minX, x), min(minY, y), max(maxX, x), max(maxY, y)
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] >= 0 && n[0] < w && n[1] >= 0 && n[1] < h {
+					if j := n[1]*w + n[0]; ink[j] && !visited[j] {
+						visited[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		inkPixels += count
+		boxW, boxH := maxX-minX+1, maxY-minY+1
+		fill := float64(count) / float64(boxW*boxH)
+		if boxH >= _TEXT_MIN_CHAR_HEIGHT && boxH*10 <= h*9 && boxW*10 <= w*9 && fill >= 0.1 && fill <= 0.9 {
+			chars++
+			charPixels += count
+		}
+	}
+	if chars == 0 {
+		return 0
+	}
+	c := float64(chars)
+	return c / (c + 2) * float64(charPixels) / float64(inkPixels)
+}
+
+// otsu returns the threshold of Otsu's method for the histogram of n
+// pixels, pixels up to it being dark, and the mean gray levels of the dark
+// and light pixels.
+func otsu(histogram [256]int, n int) (threshold int, dark, light float64) {
+	var sum float64
+	for g, count := range histogram {
+		sum += float64(g * count)
+	}
+	var darkSum float64
+	var darkCount int
+	best := -1.0
+	for g := 0; g < 255; g++ {
+		darkCount += histogram[g]
+		darkSum += float64(g * histogram[g])
+		lightCount := n - darkCount
+		if darkCount == 0 || lightCount == 0 {
+			continue
+		}
+		darkMean := darkSum / float64(darkCount)
+		lightMean := (sum - darkSum) / float64(lightCount)
+		// between-class variance, up to a constant factor
+		variance := float64(darkCount) * float64(lightCount) * (darkMean - lightMean) * (darkMean - lightMean)
+		if variance > best {
+			best, threshold, dark, light = variance, g, darkMean, lightMean
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package baiduocr_test
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestLikelyHasText(t *testing.T) {
+	captcha, err := ioutil.ReadFile("test/fixtures/simple-captcha/3560.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hanzi, err := ioutil.ReadFile("test/fixtures/chinese/hanzi.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blank := image.NewGray(image.Rect(0, 0, 100, 50))
+	for i := range blank.Pix {
+		blank.Pix[i] = 250
+	}
+	// single pixel specks, as of a noisy blank scan
+	specks := image.NewGray(image.Rect(0, 0, 100, 50))
+	random := rand.New(rand.NewSource(1))
+	for i := range specks.Pix {
+		specks.Pix[i] = 255
+		if random.Intn(20) == 0 {
+			specks.Pix[i] = 0
+		}
+	}
+	for _, test := range []struct {
+		name    string
+		image   []byte
+		options []baiduocr.BaiduOCROption
+		want    bool
+	}{
+		{"captcha", captcha, []baiduocr.BaiduOCROption{baiduocr.SetPNGBackgroundColor(color.White)}, true},
+		{"hanzi", hanzi, nil, true},
+		{"blank", encodePNG(t, blank), nil, false},
+		{"specks", encodePNG(t, specks), nil, false},
+	} {
+		likely, confidence, err := baiduocr.LikelyHasText(test.image, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if likely != test.want || confidence < 0 || confidence > 1 {
+			t.Errorf("%s: got %v with confidence %.2f, want %v", test.name, likely, confidence, test.want)
+		}
+	}
+	if _, _, err := baiduocr.LikelyHasText([]byte("not an image")); err == nil {
+		t.Error("got no error for unsupported format")
+	}
+}
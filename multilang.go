@@ -0,0 +1,136 @@
+package baiduocr
+
+import (
+	"errors"
+	"image"
+	"sync"
+)
+
+// Values of the languagetype parameter, see ParseMultiLang. Baidu OCR accepts
+// more, such as POR, FRE, GER, ITA, SPA, RUS and KOR on some endpoints.
+const (
+	LanguageChinese  = _CHINESE
+	LanguageEnglish  = _ENGLISH
+	LanguageJapanese = _JAPANESE
+)
+
+// Read text from JPEG/PNG image once for each of langs, such as
+// LanguageChinese and LanguageEnglish, and merge the words, which sometimes
+// beats a single pass on captchas mixing languages and digits. The passes run
+// concurrently and each is a separate API call, so the image costs
+// len(langs) calls of the quota. Words whose boxes overlap by at least half
+// of the smaller box are considered the same detection, and the one with the
+// higher confidence is kept, or if the endpoint reports no confidence, like
+// OCR, the one of the language coming first in langs. Words without a
+// bounding box cannot be matched and are only taken from the first language
+// recognizing any text. A pass recognizing no text is not an error, unless
+// all of them do.
+func (ocr OCR) ParseMultiLang(imageBytes []byte, langs []string, options ...BaiduOCROption) (words []Word, err error) {
+	return parseMultiLang(langs, options, func(options []BaiduOCROption) (words []Word, err error) {
+		words, _, err = ocr.ParseDetailed(imageBytes, options...)
+		return
+	})
+}
+
+// Same as OCR.ParseMultiLang, with the named endpoint. Use RequestConfidence
+// for the merge to prefer the most confident detections.
+func (aip *AipOCR) ParseMultiLang(endpoint string, imageBytes []byte, langs []string, options ...BaiduOCROption) (words []Word, err error) {
+	return parseMultiLang(langs, options, func(options []BaiduOCROption) (words []Word, err error) {
+		words, _, err = aip.ParseDetailed(endpoint, imageBytes, options...)
+		return
+	})
+}
+
+func parseMultiLang(langs []string, options []BaiduOCROption, parse func([]BaiduOCROption) ([]Word, error)) (words []Word, err error) {
+	if len(langs) == 0 {
+		err = errors.New("no language to recognize")
+		return
+	}
+	passes := make([][]Word, len(langs))
+	errs := make([]error, len(langs))
+	var wg sync.WaitGroup
+	for i, lang := range langs {
+		wg.Add(1)
+		go func(i int, lang string) {
+			defer wg.Done()
+			setLang := BaiduOCROption{func(option *baiduOCROption) { option.languageType = lang }}
+			passes[i], errs[i] = parse(append(options[:len(options):len(options)], setLang))
+		}(i, lang)
+	}
+	wg.Wait()
+
+	unlocated := true
+	for i, pass := range passes {
+		if errs[i] != nil && !errors.Is(errs[i], ErrNoText) {
+			err = errs[i]
+			return
+		}
+		for _, word := range pass {
+			if word.Rect.Empty() {
+				if unlocated {
+					words = append(words, word)
+				}
+				continue
+			}
+			words = mergeWord(words, word)
+		}
+		if len(pass) > 0 {
+			unlocated = false
+		}
+	}
+	if len(words) == 0 {
+		err = newOptions(options).formatted(noTextError(""))
+	}
+	return
+}
+
+// mergeWord adds word to words, unless it overlaps words at least as
+// confident, in which case it is dropped, or else it replaces them.
+func mergeWord(words []Word, word Word) []Word {
+	var overlapped []int
+	for i, other := range words {
+		if sameDetection(word.Rect, other.Rect) {
+			if !word.HasConfidence || other.Confidence >= word.Confidence {
+				return words
+			}
+			overlapped = append(overlapped, i)
+		}
+	}
+	if len(overlapped) == 0 {
+		return append(words, word)
+	}
+	// replace the first overlapped word to keep the order, drop the others
+	merged := words[:0:0]
+	for i, other := range words {
+		switch {
+		case i == overlapped[0]:
+			merged = append(merged, word)
+		case !contains(overlapped, i):
+			merged = append(merged, other)
+		}
+	}
+	return merged
+}
+
+// sameDetection reports whether a and b overlap by at least half of the
+// smaller of them.
+func sameDetection(a, b image.Rectangle) bool {
+	inter := a.Intersect(b)
+	if inter.Empty() {
+		return false
+	}
+	smaller := a.Dx() * a.Dy()
+	if area := b.Dx() * b.Dy(); area < smaller {
+		smaller = area
+	}
+	return 2*inter.Dx()*inter.Dy() >= smaller
+}
+
+func contains(indexes []int, i int) bool {
+	for _, index := range indexes {
+		if index == i {
+			return true
+		}
+	}
+	return false
+}
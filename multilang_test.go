@@ -0,0 +1,63 @@
+package baiduocr_test
+
+import (
+	"fmt"
+	"image"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseMultiLang(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		switch r.FormValue("languagetype") {
+		case baiduocr.LanguageChinese:
+			return []baiduocr.Word{{Text: "中", Rect: image.Rect(0, 0, 10, 10)}, {Text: "1", Rect: image.Rect(10, 0, 20, 10)}}
+		case baiduocr.LanguageEnglish:
+			return []baiduocr.Word{{Text: "l", Rect: image.Rect(11, 1, 19, 10)}, {Text: "x", Rect: image.Rect(20, 0, 30, 10)}}
+		}
+		return nil
+	})
+	words, err := ocr.ParseMultiLang(fakeJPEG, []string{baiduocr.LanguageChinese, baiduocr.LanguageEnglish, baiduocr.LanguageJapanese})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, word := range words {
+		texts = append(texts, word.Text)
+	}
+	if got := fmt.Sprint(texts); got != "[中 1 x]" {
+		t.Errorf("got %s", got)
+	}
+	if _, err := ocr.ParseMultiLang(fakeJPEG, []string{baiduocr.LanguageJapanese}); err == nil {
+		t.Error("want error when no pass recognizes text")
+	}
+}
+
+func TestAipParseMultiLang(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		word := func(text string, left int, confidence float64) interface{} {
+			return map[string]interface{}{
+				"words":       text,
+				"location":    map[string]int{"left": left, "top": 0, "width": 10, "height": 10},
+				"probability": map[string]float64{"average": confidence},
+			}
+		}
+		if r.FormValue("language_type") == baiduocr.LanguageChinese {
+			return map[string]interface{}{"words_result": []interface{}{word("O", 0, 0.5), word("中", 10, 0.9)}}
+		}
+		return map[string]interface{}{"words_result": []interface{}{word("0", 0, 0.8), word("p", 10, 0.3)}}
+	})
+	words, err := aip.ParseMultiLang("general", fakeJPEG, []string{baiduocr.LanguageChinese, baiduocr.LanguageEnglish}, baiduocr.RequestConfidence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, word := range words {
+		texts = append(texts, word.Text)
+	}
+	if got := fmt.Sprint(texts); got != "[0 中]" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,186 @@
+package baiduocr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// ErrTooManyPixels is returned when the image has more pixels than allowed
+// by SetMaxPixels. The returned error wraps it with the dimensions, test for
+// it with errors.Is.
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Option to reject images of more than n pixels (width×height) with
+// ErrTooManyPixels, such as decompression bombs whose few bytes decode to
+// gigabytes. The dimensions are read from the header of the image, so the
+// image is rejected before it is decoded. It applies to every image decoded
+// or submitted, including JPEG images submitted unchanged, and to the images
+// made by preprocessing options, such as SetFixedCanvas. The default is no
+// limit.
+func SetMaxPixels(n int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.maxPixels = n }}
+}
+
+// imageDecoders decode the supported formats by content type, as detected
+// by http.DetectContentType, into images without transparency.
+var imageDecoders = map[string]func([]byte, baiduOCROption) (image.Image, error){
+	"image/jpeg": func(imageBytes []byte, _ baiduOCROption) (image.Image, error) {
+		return jpeg.Decode(bytes.NewReader(imageBytes))
+	},
+	"image/png": func(imageBytes []byte, opts baiduOCROption) (image.Image, error) {
+		img, err := png.Decode(bytes.NewReader(imageBytes))
+		if err != nil {
+			return nil, err
+		}
+		return opts.flatten(img), nil
+	},
+	"image/gif": func(imageBytes []byte, opts baiduOCROption) (image.Image, error) {
+		img, err := decodeGIFFrame(bytes.NewReader(imageBytes), opts.frameIndex)
+		if err != nil {
+			return nil, err
+		}
+		return opts.flatten(img), nil
+	},
+}
+
+// Convert an image of any supported format (JPEG, PNG or GIF) to the JPEG
+// image the Parse methods submit, with the options converting and
+// preprocessing images applied, such as SetPNGBackgroundColor,
+// SetFrameIndex and SetFixedCanvas. JPEG images without preprocessing
+// options are returned unchanged. Client settings such as
+// OCR.DefaultPNGBackground are not applied. An error wrapping
+// ErrUnsupportedFormat is returned for other formats.
+func NormalizeToJPEG(imageBytes []byte, options ...BaiduOCROption) (jpegBytes []byte, err error) {
+	return toJPEG(imageBytes, newOptions(options))
+}
+
+// Output formats of Normalize.
+type ImageFormat int
+
+const (
+	// JPEG, as submitted to Baidu OCR
+	FormatJPEG ImageFormat = iota
+	// Lossless PNG, for inspecting the result of preprocessing without the
+	// artifacts of JPEG compression
+	FormatPNG
+)
+
+// Same as NormalizeToJPEG, with the image encoded in format instead. With
+// FormatPNG, the image is always decoded and encoded again, JPEG images
+// included, and the JPEG encoding options such as SetNoChromaSubsampling
+// have no effect. The Parse methods submit JPEG whatever the format used
+// here, unless SmartFormat lets AipOCR choose PNG.
+func Normalize(imageBytes []byte, format ImageFormat, options ...BaiduOCROption) (output []byte, err error) {
+	opts := newOptions(options)
+	switch format {
+	case FormatJPEG:
+		return toJPEG(imageBytes, opts)
+	case FormatPNG:
+	default:
+		err = fmt.Errorf("unknown image format %d", format)
+		return
+	}
+	var img image.Image
+	img, err = normalizedImage(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	err = png.Encode(&buffer, img)
+	output = buffer.Bytes()
+	return
+}
+
+func toJPEG(imageBytes []byte, opts baiduOCROption) (jpegBytes []byte, err error) {
+	if contentType := http.DetectContentType(imageBytes); contentType == "image/jpeg" && len(opts.transforms) == 0 {
+		err = opts.checkContentType(imageBytes, contentType)
+		if err == nil {
+			err = opts.checkPixels(imageBytes)
+		}
+		jpegBytes = imageBytes
+		return
+	}
+	var img image.Image
+	img, err = normalizedImage(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	err = opts.contextErr()
+	if err != nil {
+		return
+	}
+	done := opts.track(encodePhase)
+	jpegBytes, err = encodeJPEGBytes(img, opts)
+	done()
+	return
+}
+
+// normalizedImage returns the image decoded and preprocessed, ready to be
+// encoded.
+func normalizedImage(imageBytes []byte, opts baiduOCROption) (img image.Image, err error) {
+	img, err = decodeImage(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	err = opts.contextErr()
+	if err != nil {
+		return
+	}
+	done := opts.track(preprocessPhase)
+	img = opts.scaleToDPI(img, imageBytes)
+	img, err = opts.preprocess(img)
+	done()
+	if err != nil {
+		return
+	}
+	err = opts.checkSize(img.Bounds().Size())
+	return
+}
+
+func decodeImage(imageBytes []byte, opts baiduOCROption) (img image.Image, err error) {
+	contentType := http.DetectContentType(imageBytes)
+	err = opts.checkContentType(imageBytes, contentType)
+	if err != nil {
+		return
+	}
+	decode, ok := imageDecoders[contentType]
+	if !ok {
+		err = unsupportedFormatError(contentType)
+		return
+	}
+	err = opts.checkPixels(imageBytes)
+	if err != nil {
+		return
+	}
+	done := opts.track(decodePhase)
+	img, err = decode(imageBytes, opts)
+	done()
+	return
+}
+
+// checkPixels returns ErrTooManyPixels if the header of the image tells it
+// has more pixels than allowed by SetMaxPixels.
+func (opts baiduOCROption) checkPixels(imageBytes []byte) error {
+	if opts.maxPixels <= 0 {
+		return nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(imageBytes))
+	if err != nil {
+		return err
+	}
+	return opts.checkSize(image.Pt(config.Width, config.Height))
+}
+
+// checkSize returns ErrTooManyPixels if size has more pixels than allowed by
+// SetMaxPixels.
+func (opts baiduOCROption) checkSize(size image.Point) error {
+	if opts.maxPixels > 0 && int64(size.X)*int64(size.Y) > int64(opts.maxPixels) {
+		return fmt.Errorf("%w: %d×%d is more than %d", ErrTooManyPixels, size.X, size.Y, opts.maxPixels)
+	}
+	return nil
+}
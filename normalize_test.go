@@ -0,0 +1,242 @@
+package baiduocr_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestNormalizeToJPEG(t *testing.T) {
+	transparent := encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 8, 8)))
+	jpegBytes, err := baiduocr.NormalizeToJPEG(transparent, baiduocr.SetPNGBackgroundColor(color.White))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(jpegBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := gray(img, 4, 4); g < 250 {
+		t.Errorf("background is %d, want white", g)
+	}
+
+	if same, err := baiduocr.NormalizeToJPEG(jpegBytes); err != nil || !bytes.Equal(same, jpegBytes) {
+		t.Errorf("want JPEG unchanged without preprocessing, got error %v", err)
+	}
+	resized, err := baiduocr.NormalizeToJPEG(jpegBytes, baiduocr.SetFixedCanvas(16, 4, color.Black))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, err := jpeg.DecodeConfig(bytes.NewReader(resized)); err != nil || config.Width != 16 || config.Height != 4 {
+		t.Errorf("got %+v, %v, want 16x4", config, err)
+	}
+
+	if _, err := baiduocr.NormalizeToJPEG([]byte("<svg></svg>")); !errors.Is(err, baiduocr.ErrUnsupportedFormat) {
+		t.Errorf("got %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestSetJPEGEncoder(t *testing.T) {
+	src := encodePNG(t, image.NewGray(image.Rect(0, 0, 8, 8)))
+	var qualities []int
+	// the standard encoder behind the hook, as a custom one would be
+	encoder := baiduocr.SetJPEGEncoder(func(w io.Writer, img image.Image, quality int) error {
+		qualities = append(qualities, quality)
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	})
+	custom, err := baiduocr.NormalizeToJPEG(src, encoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(qualities) != "[100]" {
+		t.Errorf("encoder called with qualities %v, want [100]", qualities)
+	}
+	if config, err := jpeg.DecodeConfig(bytes.NewReader(custom)); err != nil || config.Width != 8 || config.Height != 8 {
+		t.Errorf("got %+v, %v, want a valid 8x8 JPEG", config, err)
+	}
+	standard, err := baiduocr.NormalizeToJPEG(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(custom, standard) {
+		t.Error("the hook with the standard encoder differs from the default")
+	}
+
+	notJPEG := baiduocr.SetJPEGEncoder(func(w io.Writer, img image.Image, quality int) error {
+		return png.Encode(w, img)
+	})
+	if _, err := baiduocr.NormalizeToJPEG(src, notJPEG); err == nil || !strings.Contains(err.Error(), "image/png") {
+		t.Errorf("got %v, want an error for PNG output", err)
+	}
+}
+
+func TestNormalizePNG(t *testing.T) {
+	// a pattern of single pixels that JPEG would blur
+	src := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i % 2 * 255)
+	}
+	pngBytes, err := baiduocr.Normalize(encodePNG(t, src), baiduocr.FormatPNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(pngBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 8; x++ {
+		if g, want := gray(img, x, 3), src.GrayAt(x, 3).Y; g != want {
+			t.Errorf("pixel %d is %d, want %d", x, g, want)
+		}
+	}
+	resized, err := baiduocr.Normalize(pngBytes, baiduocr.FormatPNG, baiduocr.SetFixedCanvas(16, 16, color.White))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, err := png.DecodeConfig(bytes.NewReader(resized)); err != nil || config.Width != 16 || config.Height != 16 {
+		t.Errorf("got %+v, %v, want 16x16 PNG", config, err)
+	}
+	if jpegBytes, err := baiduocr.Normalize(pngBytes, baiduocr.FormatJPEG); err != nil || http.DetectContentType(jpegBytes) != "image/jpeg" {
+		t.Errorf("got %v, want JPEG by default", err)
+	}
+	if _, err := baiduocr.Normalize(pngBytes, baiduocr.ImageFormat(9)); err == nil {
+		t.Error("got no error for unknown format")
+	}
+}
+
+func TestSetMaxPixels(t *testing.T) {
+	// a PNG whose header claims 100000x100000 pixels, with a valid checksum
+	bomb := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	ihdr := bomb[8+4 : 8+4+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	binary.BigEndian.PutUint32(ihdr[8:], 100000)
+	binary.BigEndian.PutUint32(bomb[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+
+	ocr, _ := submittedImage(t)
+	if _, err := ocr.ParseImage(bomb, baiduocr.SetMaxPixels(1000000)); !errors.Is(err, baiduocr.ErrTooManyPixels) {
+		t.Errorf("got %v, want ErrTooManyPixels", err)
+	}
+	if _, err := baiduocr.NormalizeToJPEG(bomb, baiduocr.SetMaxPixels(1000000)); !errors.Is(err, baiduocr.ErrTooManyPixels) {
+		t.Errorf("got %v from NormalizeToJPEG, want ErrTooManyPixels", err)
+	}
+	small := encodePNG(t, image.NewGray(image.Rect(0, 0, 100, 100)))
+	if _, err := ocr.ParseImage(small, baiduocr.SetMaxPixels(10000)); err != nil {
+		t.Error(err)
+	}
+	// images made by preprocessing are checked too
+	if _, err := ocr.ParseImage(small, baiduocr.SetMaxPixels(10000), baiduocr.SetFixedCanvas(200, 200, color.White)); !errors.Is(err, baiduocr.ErrTooManyPixels) {
+		t.Errorf("got %v, want ErrTooManyPixels for the canvas", err)
+	}
+}
+
+// withChunk returns the PNG with a chunk of kind and data after its header.
+func withChunk(pngBytes []byte, kind string, data []byte) []byte {
+	chunk := make([]byte, 4+4+len(data)+4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], kind)
+	copy(chunk[8:], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):], crc32.ChecksumIEEE(chunk[4:8+len(data)]))
+	// the header chunk is 8+4+4+13+4 bytes from the start
+	end := 8 + 4 + 4 + 13 + 4
+	return append(append(append([]byte(nil), pngBytes[:end]...), chunk...), pngBytes[end:]...)
+}
+
+// withPHYs returns the PNG with a pHYs chunk of the pixels per unit.
+func withPHYs(pngBytes []byte, perUnit uint32, unit byte) []byte {
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint32(data, perUnit)
+	binary.BigEndian.PutUint32(data[4:], perUnit)
+	data[8] = unit
+	return withChunk(pngBytes, "pHYs", data)
+}
+
+func TestSetTargetScale(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	screenshot := encodePNG(t, image.NewGray(image.Rect(0, 0, 100, 60)))
+	for _, test := range []struct {
+		image []byte
+		want  image.Point
+	}{
+		// 192 dots per inch is 7559 pixels per meter
+		{withPHYs(screenshot, 7559, 1), image.Pt(50, 30)},
+		{withPHYs(screenshot, 1890, 1), image.Pt(200, 120)},
+		{withPHYs(screenshot, 7559, 0), image.Pt(100, 60)},
+		{screenshot, image.Pt(100, 60)},
+	} {
+		if _, err := ocr.ParseImage(test.image, baiduocr.SetTargetScale(96)); err != nil {
+			t.Fatal(err)
+		}
+		if size := submitted().Bounds().Size(); size != test.want {
+			t.Errorf("size = %v, want %v", size, test.want)
+		}
+	}
+}
+
+func TestContentTypeChecks(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	pngBytes := encodePNG(t, image.NewGray(image.Rect(0, 0, 8, 8)))
+	var jpegBuffer bytes.Buffer
+	if err := jpeg.Encode(&jpegBuffer, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		image   []byte
+		options []baiduocr.BaiduOCROption
+		fails   bool
+	}{
+		{pngBytes, []baiduocr.BaiduOCROption{baiduocr.SetExpectedContentType("image/png")}, false},
+		{pngBytes, []baiduocr.BaiduOCROption{baiduocr.SetExpectedContentType("image/jpeg")}, true},
+		{jpegBuffer.Bytes(), []baiduocr.BaiduOCROption{baiduocr.SetExpectedContentType("image/png")}, true},
+		{jpegBuffer.Bytes(), []baiduocr.BaiduOCROption{baiduocr.StrictContentType()}, false},
+		{pngBytes, []baiduocr.BaiduOCROption{baiduocr.StrictContentType()}, false},
+		// detected as JPEG from its first bytes, but not decodable
+		{fakeJPEG, nil, false},
+		{fakeJPEG, []baiduocr.BaiduOCROption{baiduocr.StrictContentType()}, true},
+		{pngBytes[:20], []baiduocr.BaiduOCROption{baiduocr.StrictContentType()}, true},
+	} {
+		_, err := ocr.ParseImage(test.image, test.options...)
+		if got := errors.Is(err, baiduocr.ErrContentTypeMismatch); got != test.fails {
+			t.Errorf("%s: got %v, want mismatch %t", http.DetectContentType(test.image), err, test.fails)
+		}
+	}
+}
+
+func TestICCProfile(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	var warnings []string
+	ocr.LogFunc = func(format string, v ...interface{}) { warnings = append(warnings, fmt.Sprintf(format, v...)) }
+	plain := encodePNG(t, image.NewGray(image.Rect(0, 0, 8, 8)))
+	// the name, a null separator, the compression method and a profile,
+	// which is not read
+	profile := func(name string) []byte { return append([]byte(name), 0, 0, 'x') }
+	for _, test := range []struct {
+		image   []byte
+		name    string
+		warning bool
+	}{
+		{plain, "", false},
+		{withChunk(plain, "iCCP", profile("sRGB IEC61966-2.1")), "sRGB IEC61966-2.1", false},
+		{withChunk(plain, "iCCP", profile("Display P3")), "Display P3", true},
+	} {
+		warnings = nil
+		_, meta, err := ocr.ParseDetailed(test.image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.ICCProfile != test.name || (len(warnings) == 1) != test.warning {
+			t.Errorf("got profile %q, warnings %q, want %q, warning %t", meta.ICCProfile, warnings, test.name, test.warning)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package baiduocr
+
+import (
+	"runtime"
+	"sync"
+)
+
+func withPreprocessParallelism(n int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.preprocessParallelism = n }}
+}
+
+// newOptions is the same as the newOptions function, but defaults the
+// parallelism to PreprocessParallelism.
+func (aip *AipOCR) newOptions(options []BaiduOCROption) baiduOCROption {
+	return newOptions(append([]BaiduOCROption{withPreprocessParallelism(aip.PreprocessParallelism)}, options...))
+}
+
+// parallelism returns the number of goroutines preprocessing may use, see
+// OCR.PreprocessParallelism.
+func (opts baiduOCROption) parallelism() int {
+	if opts.preprocessParallelism > 0 {
+		return opts.preprocessParallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parallelRows calls f with bands of consecutive rows from 0 to h, in at
+// most n goroutines, and returns once they are all done. With n of 1, f is
+// called in the calling goroutine with all the rows.
+func parallelRows(n, h int, f func(y0, y1 int)) {
+	if n > h {
+		n = h
+	}
+	if n <= 1 {
+		f(0, h)
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(y0, y1 int) {
+			defer wg.Done()
+			f(y0, y1)
+		}(h*i/n, h*(i+1)/n)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+package baiduocr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ErrPDFUnsupported is returned by ParsePDF when the package is built
+// without the pdf build tag.
+var ErrPDFUnsupported = errors.New("PDF support requires building with -tags pdf")
+
+const _PDF_DPI = 150
+
+// Read text from each page of a PDF document, returning the results page by
+// page. Pages are rendered to images at 150 DPI and recognized one after
+// another, so a document costs one API call per page. Pages where Baidu finds
+// no text, or skipped by SkipBlankImages, have empty results. SetMaxPixels
+// is checked against the size of each page before it is rendered, and so is
+// OCR.OperationTimeout, which bounds the whole document.
+//
+// Rendering uses MuPDF through github.com/gen2brain/go-fitz, which needs cgo
+// and is only compiled in with the pdf build tag:
+//
+//	go build -tags pdf
+//
+// Without the tag, ParsePDF returns ErrPDFUnsupported.
+func (ocr OCR) ParsePDF(pdfBytes []byte, options ...BaiduOCROption) (pages [][]string, err error) {
+	opts, timedOut, cancel := ocr.newOptions(options).withOperationTimeout(ocr.OperationTimeout)
+	defer cancel()
+	defer func() { err = timedOut(err) }()
+	check := func(size image.Point) error {
+		if err := opts.contextErr(); err != nil {
+			return err
+		}
+		return opts.checkSize(size)
+	}
+	err = renderPDF(pdfBytes, _PDF_DPI, check, func(i int, img image.Image) (err error) {
+		img, err = opts.preprocess(img)
+		if err == nil {
+			err = opts.checkSize(img.Bounds().Size())
+		}
+		if err == nil {
+			err = opts.contextErr()
+		}
+		if err != nil {
+			return
+		}
+		var jpegBytes []byte
+		jpegBytes, err = encodeJPEGBytes(img, opts)
+		if err != nil {
+			return
+		}
+		var words []Word
+		words, _, err = ocr.parseJPEG(jpegBytes, opts)
+		if errors.Is(err, ErrBlankImage) || errors.Is(err, ErrNoText) {
+			err = nil
+		} else if err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+		pages = append(pages, wordTexts(words))
+		return
+	})
+	return
+}
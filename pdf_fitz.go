@@ -0,0 +1,41 @@
+//go:build pdf
+// +build pdf
+
+package baiduocr
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+func renderPDF(pdfBytes []byte, dpi float64, check func(image.Point) error, page func(int, image.Image) error) (err error) {
+	var doc *fitz.Document
+	doc, err = fitz.NewFromMemory(pdfBytes)
+	if err != nil {
+		return
+	}
+	defer doc.Close()
+	for i := 0; i < doc.NumPage(); i++ {
+		// the bounds are in points, 72 per inch
+		var bounds image.Rectangle
+		bounds, err = doc.Bound(i)
+		if err != nil {
+			return
+		}
+		size := image.Pt(int(float64(bounds.Dx())*dpi/72), int(float64(bounds.Dy())*dpi/72))
+		if err = check(size); err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+		var img *image.RGBA
+		img, err = doc.ImageDPI(i, dpi)
+		if err != nil {
+			return
+		}
+		if err = page(i, img); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,69 @@
+//go:build pdf
+// +build pdf
+
+package baiduocr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParsePDF(t *testing.T) {
+	var calls int32
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil
+		}
+		return []string{"ok"}
+	})
+	// two blank pages of 144x72 points, 300x150 pixels at 150 DPI
+	pdf, err := ioutil.ReadFile("test/fixtures/pdf/two-pages.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := ocr.ParsePDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(pages) != "[[] [ok]]" {
+		t.Errorf("got %v, want the page without text empty", pages)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if _, err := ocr.ParsePDF(pdf, baiduocr.SetMaxPixels(300*150-1)); !errors.Is(err, baiduocr.ErrTooManyPixels) {
+		t.Errorf("got %v, want ErrTooManyPixels", err)
+	}
+	if calls != 0 {
+		t.Errorf("sent %d requests, want the page rejected before it is rendered", calls)
+	}
+}
+
+func TestParsePDFOperationTimeout(t *testing.T) {
+	var calls int32
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return []string{"late"}
+	})
+	ocr.OperationTimeout = 50 * time.Millisecond
+	pdf, err := ioutil.ReadFile("test/fixtures/pdf/two-pages.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ocr.ParsePDF(pdf)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "operation timeout exceeded") {
+		t.Errorf("got %v, want the operation timeout", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("sent %d requests, want the second page not sent", n)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !pdf
+// +build !pdf
+
+package baiduocr
+
+import (
+	"image"
+)
+
+func renderPDF(pdfBytes []byte, dpi float64, check func(image.Point) error, page func(int, image.Image) error) error {
+	return ErrPDFUnsupported
+}
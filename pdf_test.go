@@ -0,0 +1,22 @@
+//go:build !pdf
+// +build !pdf
+
+package baiduocr_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParsePDFUnsupported(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		t.Error("no request is to be sent")
+		return nil
+	})
+	if _, err := ocr.ParsePDF([]byte("%PDF-1.4\n")); !errors.Is(err, baiduocr.ErrPDFUnsupported) {
+		t.Errorf("got %v, want ErrPDFUnsupported", err)
+	}
+}
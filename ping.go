@@ -0,0 +1,48 @@
+package baiduocr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// ErrInvalidAPIKey is returned by Ping when the API key is missing, empty or
+// unknown to Baidu. The returned error wraps it with the error of Baidu, test
+// for it with errors.Is.
+var ErrInvalidAPIKey = errors.New("API key is invalid")
+
+// apistore errNum meaning the API key is missing, empty or unknown
+var apiStoreKeyErrors = map[int]bool{
+	300202: true,
+	300203: true,
+	300204: true,
+}
+
+// Check that Baidu OCR is reachable and accepts the API key, without using a
+// real image, such as in a readiness probe. A 1×1 white image is submitted,
+// in which Baidu finding no text means success. If the API key is rejected,
+// the error wraps ErrInvalidAPIKey. Any other error means Baidu OCR could
+// not be reached or is failing. Like other calls, it counts as a request
+// against the quota of the API key.
+func (ocr OCR) Ping(options ...BaiduOCROption) (err error) {
+	opts := ocr.newOptions(options)
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.SetGray(0, 0, color.Gray{255})
+	var jpegBytes []byte
+	jpegBytes, err = encodeJPEGBytes(img, opts)
+	if err != nil {
+		return
+	}
+	_, _, err = ocr.requestWithRetries(jpegBytes, opts)
+	var ocrErr OCRError
+	switch {
+	case err == nil, errors.Is(err, ErrNoText):
+		err = nil
+	case errors.As(err, &ocrErr) && apiStoreKeyErrors[ocrErr.Code]:
+		err = fmt.Errorf("%w: %v", ErrInvalidAPIKey, err)
+	default:
+		err = fmt.Errorf("BaiduOCR is unavailable: %w", err)
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package baiduocr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+)
+
+const (
+	_PNG_SIGNATURE = "\x89PNG\r\n\x1a\n"
+	// pHYs unit of pixels per meter, the only one defined besides unknown
+	_PNG_UNIT_METER   = 1
+	_INCHES_PER_METER = 1 / 0.0254
+)
+
+// Option to scale PNG images to dpi dots per inch, as given by their pHYs
+// chunk, such as screenshots of high-density displays, whose text would
+// otherwise be twice or three times as large as on other screenshots. The
+// pHYs chunk gives the pixels per meter of each axis; the resolution in dots
+// per inch is that divided by 39.37 (inches per meter), and each axis is
+// scaled by dpi divided by its resolution, rounded to the nearest pixel. For
+// example, a 2880×1800 screenshot at 144 dots per inch with SetTargetScale(72)
+// is submitted at 1440×900. Images without a pHYs chunk, with a pHYs chunk of
+// unknown unit, or not PNG, are not scaled. The image is scaled before the
+// preprocessing options.
+func SetTargetScale(dpi float64) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.targetDPI = dpi }}
+}
+
+// scaleToDPI returns img scaled as set by SetTargetScale, using the pHYs
+// chunk of imageBytes.
+func (opts baiduOCROption) scaleToDPI(img image.Image, imageBytes []byte) image.Image {
+	if opts.targetDPI <= 0 {
+		return img
+	}
+	dpiX, dpiY, ok := pngDPI(imageBytes)
+	if !ok {
+		return img
+	}
+	size := img.Bounds().Size()
+	w := int(math.Round(float64(size.X) * opts.targetDPI / dpiX))
+	h := int(math.Round(float64(size.Y) * opts.targetDPI / dpiY))
+	if w < 1 || h < 1 || w == size.X && h == size.Y {
+		return img
+	}
+	return resize(img, w, h, opts.parallelism())
+}
+
+// pngDPI returns the resolution in dots per inch of each axis given by the
+// pHYs chunk of a PNG image, or false if there is none or its unit is
+// unknown.
+func pngDPI(imageBytes []byte) (dpiX, dpiY float64, ok bool) {
+	data, found := pngChunk(imageBytes, "pHYs")
+	if !found || len(data) != 9 || data[8] != _PNG_UNIT_METER {
+		return
+	}
+	x, y := binary.BigEndian.Uint32(data), binary.BigEndian.Uint32(data[4:])
+	if x == 0 || y == 0 {
+		return
+	}
+	return float64(x) / _INCHES_PER_METER, float64(y) / _INCHES_PER_METER, true
+}
+
+// pngChunk returns the data of the first chunk of kind in a PNG image, or
+// false if it is not PNG or has no such chunk before the image data, where
+// the ancillary chunks describing the whole image are. The chunks after are
+// not read.
+func pngChunk(imageBytes []byte, kind string) (data []byte, ok bool) {
+	if !bytes.HasPrefix(imageBytes, []byte(_PNG_SIGNATURE)) {
+		return
+	}
+	chunks := imageBytes[len(_PNG_SIGNATURE):]
+	for len(chunks) >= 12 {
+		length := binary.BigEndian.Uint32(chunks)
+		chunkKind := string(chunks[4:8])
+		if uint64(length)+12 > uint64(len(chunks)) || chunkKind == "IDAT" {
+			return
+		}
+		if chunkKind == kind {
+			return chunks[8 : 8+length], true
+		}
+		chunks = chunks[12+length:]
+	}
+	return
+}
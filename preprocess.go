@@ -0,0 +1,373 @@
+package baiduocr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// transform is a preprocessing step applied to the decoded image before it is
+// encoded to JPEG and submitted. It is given the options of the call.
+type transform func(image.Image, baiduOCROption) (image.Image, error)
+
+// Option to fit the image into a canvas of exactly w×h pixels. The image is
+// scaled up or down as much as possible without changing its aspect ratio
+// and centered, the rest of the canvas is filled with fill. Preprocessing
+// options are applied in the order they are given.
+func SetFixedCanvas(w, h int, fill color.Color) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.transforms = append(option.transforms, func(img image.Image, opts baiduOCROption) (image.Image, error) {
+			return fixedCanvas(img, w, h, fill, opts.parallelism())
+		})
+	}}
+}
+
+// Option to remove salt-and-pepper noise, such as the specks of captchas, with
+// a median filter: the image is converted to grayscale and each pixel is
+// replaced by the median gray level of the (2×radius+1)² pixels around it,
+// the edges of the image being extended. Specks smaller than about half the
+// window disappear while the edges of wider strokes are kept, so a radius of
+// 1 or 2 is enough for most captchas; larger radii erase thin text. The
+// filter takes O(w×h×(radius+256)) time, as the window is slid along each row
+// with a histogram. Preprocessing options are applied in the order they are
+// given.
+func SetMedianFilter(radius int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.transforms = append(option.transforms, func(img image.Image, opts baiduOCROption) (image.Image, error) {
+			return medianFilter(img, radius, opts.parallelism())
+		})
+	}}
+}
+
+// Option to keep only the pixels of the target color, such as captchas whose
+// answer is drawn in one color among distractors of other colors. Pixels
+// whose color is farther from target than tolerance are replaced by the PNG
+// background color, or white if none is set. The distance is the Euclidean
+// distance of the RGB components scaled so that black and white are 1 apart,
+// so a tolerance of 0 keeps the exact color only, and around 0.2 keeps the
+// shades of anti-aliased edges. Colors are read as sRGB, see
+// ResultMeta.ICCProfile. Preprocessing options are applied in the order they
+// are given.
+func SetColorFilter(target color.Color, tolerance float64) BaiduOCROption {
+	return SetColorFilters([]color.Color{target}, tolerance)
+}
+
+// Option to keep only the pixels of any of the target colors, such as
+// captchas whose answer is drawn in several colors among distractors of
+// others. It is SetColorFilter with a pixel kept if it is within tolerance of
+// one target. Options given after it, such as SetMedianFilter, are applied to
+// the filtered image.
+func SetColorFilters(targets []color.Color, tolerance float64) BaiduOCROption {
+	targets = append([]color.Color(nil), targets...)
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.transforms = append(option.transforms, func(img image.Image, opts baiduOCROption) (image.Image, error) {
+			background := opts.pngBackgroundColor
+			if background == nil {
+				background = color.White
+			}
+			return colorFilter(img, targets, tolerance, background, opts.parallelism()), nil
+		})
+	}}
+}
+
+// Orientation is the shape of an image, see NormalizeToOrientation.
+type Orientation int
+
+// Values of Orientation. A square image is both.
+const (
+	// The image is at least as tall as it is wide
+	OrientationPortrait Orientation = iota + 1
+	// The image is at least as wide as it is tall
+	OrientationLandscape
+)
+
+// Option to make sure the image has orientation o, rotating it 90° clockwise
+// if it has the other one, such as to always submit portrait scans of pages
+// whatever way the scanner output them. This package detects no rotation of
+// its own, so the image is rotated as it is up to this option: give it after
+// the options rotating the image to have it apply to their result.
+// Preprocessing options are applied in the order they are given.
+func NormalizeToOrientation(o Orientation) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.transforms = append(option.transforms, func(img image.Image, _ baiduOCROption) (image.Image, error) {
+			size := img.Bounds().Size()
+			switch o {
+			case OrientationPortrait:
+				if size.X > size.Y {
+					return rotate90(img), nil
+				}
+			case OrientationLandscape:
+				if size.Y > size.X {
+					return rotate90(img), nil
+				}
+			default:
+				return nil, fmt.Errorf("unknown orientation %d", o)
+			}
+			return img, nil
+		})
+	}}
+}
+
+// Option to recognize only the part of the image inside rect, in pixels from
+// the top left corner of the image, such as the captcha of a screenshot. The
+// part of rect outside of the image is ignored, and the call fails if nothing
+// is left. The positions of the results are relative to the cropped image.
+// Preprocessing options are applied in the order they are given.
+func SetCropRect(rect image.Rectangle) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.transforms = append(option.transforms, func(img image.Image, _ baiduOCROption) (image.Image, error) {
+			bounds := img.Bounds()
+			rect := rect.Add(bounds.Min).Intersect(bounds)
+			if rect.Empty() {
+				return nil, errors.New("crop rectangle is outside of the image")
+			}
+			return crop(img, rect), nil
+		})
+	}}
+}
+
+// Option to recognize only the part of the image between the fractions xMin
+// and xMax of its width and yMin and yMax of its height, from its top left
+// corner, such as SetCropFractional(0.8, 0.9, 1, 1) for its bottom right
+// corner whatever its resolution. It is SetCropRect with the rectangle
+// computed from the size of the decoded image, rounded to the nearest
+// pixels. The call fails unless 0 <= xMin < xMax <= 1 and 0 <= yMin < yMax <=
+// 1, or if the rectangle is less than a pixel. Preprocessing options are
+// applied in the order they are given.
+func SetCropFractional(xMin, yMin, xMax, yMax float64) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.transforms = append(option.transforms, func(img image.Image, _ baiduOCROption) (image.Image, error) {
+			if !(0 <= xMin && xMin < xMax && xMax <= 1 && 0 <= yMin && yMin < yMax && yMax <= 1) {
+				return nil, fmt.Errorf("crop fractions %g,%g-%g,%g are not ordered within 0 to 1", xMin, yMin, xMax, yMax)
+			}
+			bounds := img.Bounds()
+			w, h := float64(bounds.Dx()), float64(bounds.Dy())
+			rect := image.Rect(
+				int(math.Round(xMin*w)), int(math.Round(yMin*h)),
+				int(math.Round(xMax*w)), int(math.Round(yMax*h)),
+			).Add(bounds.Min)
+			if rect.Empty() {
+				return nil, errors.New("crop rectangle is less than a pixel")
+			}
+			return crop(img, rect), nil
+		})
+	}}
+}
+
+// Option to recognize the alpha channel of a PNG or GIF image instead of its
+// colors. Some captchas draw the text in the same color as the background
+// and only make it more opaque, so that it barely stands out once flattened,
+// and not at all against a background of the same color. With this option
+// the image is replaced by a grayscale image where opaque pixels are black
+// and transparent pixels are white, and the background color option is
+// ignored. Only use it for such images, as the alpha channel of ordinary
+// images is blank or only outlines the shapes. It has no effect on JPEG
+// images, which have no alpha channel.
+func UseAlphaChannel() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.useAlphaChannel = true }}
+}
+
+// preprocess applies the preprocessing options to img in order, stopping
+// once the context of SetContext is done.
+func (opts baiduOCROption) preprocess(img image.Image) (image.Image, error) {
+	for _, t := range opts.transforms {
+		if err := opts.contextErr(); err != nil {
+			return nil, err
+		}
+		var err error
+		img, err = t(img, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+func fixedCanvas(img image.Image, w, h int, fill color.Color, parallelism int) (image.Image, error) {
+	if w < 1 || h < 1 {
+		return nil, errors.New("canvas width and height must be at least 1")
+	}
+	size := img.Bounds().Size()
+	if size.X < 1 || size.Y < 1 {
+		return nil, errors.New("image is empty")
+	}
+	// scale by the smaller ratio: w/size.X or h/size.Y
+	fitW, fitH := w, size.Y*w/size.X
+	if size.Y*w > h*size.X {
+		fitW, fitH = size.X*h/size.Y, h
+	}
+	if fitW < 1 {
+		fitW = 1
+	}
+	if fitH < 1 {
+		fitH = 1
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{fill}, image.Point{}, draw.Src)
+	offset := image.Pt((w-fitW)/2, (h-fitH)/2)
+	draw.Draw(canvas, image.Rectangle{offset, offset.Add(image.Pt(fitW, fitH))}, resize(img, fitW, fitH, parallelism), image.Point{}, draw.Over)
+	return canvas, nil
+}
+
+// resize scales img to w×h with bilinear interpolation, in bands of rows
+// scaled by up to parallelism goroutines.
+func resize(img image.Image, w, h, parallelism int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	parallelRows(parallelism, h, func(top, bottom int) {
+		for y := top; y < bottom; y++ {
+			// sample at pixel centers
+			fy := (float64(y)+0.5)*float64(sh)/float64(h) - 0.5
+			y0, wy := floorFrac(fy, sh)
+			for x := 0; x < w; x++ {
+				fx := (float64(x)+0.5)*float64(sw)/float64(w) - 0.5
+				x0, wx := floorFrac(fx, sw)
+				x1, y1 := min(x0+1, sw-1), min(y0+1, sh-1)
+				for c := 0; c < 4; c++ {
+					p00 := float64(src.Pix[y0*src.Stride+x0*4+c])
+					p10 := float64(src.Pix[y0*src.Stride+x1*4+c])
+					p01 := float64(src.Pix[y1*src.Stride+x0*4+c])
+					p11 := float64(src.Pix[y1*src.Stride+x1*4+c])
+					v := (p00*(1-wx)+p10*wx)*(1-wy) + (p01*(1-wx)+p11*wx)*wy
+					dst.Pix[y*dst.Stride+x*4+c] = uint8(v + 0.5)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// floorFrac returns the integer part of f clamped to [0, n-1] and its
+// fractional part.
+func floorFrac(f float64, n int) (int, float64) {
+	if f <= 0 {
+		return 0, 0
+	}
+	i := int(f)
+	if i >= n-1 {
+		return n - 1, 0
+	}
+	return i, f - float64(i)
+}
+
+// rotate90 returns img rotated 90° clockwise.
+func rotate90(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(h-1-y, x, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// alphaToGray returns the alpha channel of img as a grayscale image, opaque
+// being black.
+func alphaToGray(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, a := img.At(x, y).RGBA()
+			gray.Pix[gray.PixOffset(x, y)] = 255 - uint8(a>>8)
+		}
+	}
+	return gray
+}
+
+// medianFilter filters img in bands of rows filtered by up to parallelism
+// goroutines, each row sliding its own window.
+func medianFilter(img image.Image, radius, parallelism int) (image.Image, error) {
+	if radius < 1 {
+		return nil, errors.New("median filter radius must be at least 1")
+	}
+	bounds := img.Bounds()
+	src := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewGray(src.Rect)
+	at := func(x, y int) int {
+		return int(src.Pix[clamp(y, h)*src.Stride+clamp(x, w)])
+	}
+	half := (2*radius + 1) * (2*radius + 1) / 2
+	parallelRows(parallelism, h, func(top, bottom int) {
+		for y := top; y < bottom; y++ {
+			var histogram [256]int
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					histogram[at(dx, y+dy)]++
+				}
+			}
+			for x := 0; x < w; x++ {
+				if x > 0 {
+					// slide the window right by one column
+					for dy := -radius; dy <= radius; dy++ {
+						histogram[at(x-radius-1, y+dy)]--
+						histogram[at(x+radius, y+dy)]++
+					}
+				}
+				level, count := 0, histogram[0]
+				for count <= half {
+					level++
+					count += histogram[level]
+				}
+				dst.Pix[y*dst.Stride+x] = uint8(level)
+			}
+		}
+	})
+	return dst, nil
+}
+
+// clamp returns i clamped to [0, n-1].
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// colorFilter returns img with the pixels farther from every target than
+// tolerance replaced by background, in bands of rows filtered by up to
+// parallelism goroutines.
+func colorFilter(img image.Image, targets []color.Color, tolerance float64, background color.Color, parallelism int) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	bg := color.RGBAModel.Convert(background).(color.RGBA)
+	ts := make([]color.NRGBA, len(targets))
+	for i, target := range targets {
+		ts[i] = color.NRGBAModel.Convert(target).(color.NRGBA)
+	}
+	// compare squared distances in 8-bit units
+	limit := tolerance * tolerance * 3 * 255 * 255
+	parallelRows(parallelism, bounds.Dy(), func(top, bottom int) {
+		for y := bounds.Min.Y + top; y < bounds.Min.Y+bottom; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(dst.RGBAAt(x, y)).(color.NRGBA)
+				keep := false
+				for _, t := range ts {
+					dr, dg, db := float64(c.R)-float64(t.R), float64(c.G)-float64(t.G), float64(c.B)-float64(t.B)
+					if dr*dr+dg*dg+db*db <= limit {
+						keep = true
+						break
+					}
+				}
+				if !keep {
+					dst.SetRGBA(x, y, bg)
+				}
+			}
+		}
+	})
+	return dst
+}
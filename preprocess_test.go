@@ -0,0 +1,261 @@
+package baiduocr_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+// submittedImage returns an OCR client and a function returning the last
+// image it submitted.
+func submittedImage(t *testing.T) (baiduocr.OCR, func() image.Image) {
+	var last image.Image
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		last = requestImage(t, r)
+		return []string{"ok"}
+	})
+	return ocr, func() image.Image { return last }
+}
+
+func gray(img image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
+
+func TestSetFixedCanvas(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(src, src.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	if _, err := ocr.ParseImage(encodePNG(t, src), baiduocr.SetFixedCanvas(60, 60, color.White)); err != nil {
+		t.Fatal(err)
+	}
+	img := submitted()
+	if size := img.Bounds().Size(); size != image.Pt(60, 60) {
+		t.Fatalf("size = %v, want 60x60", size)
+	}
+	// 100x50 fits as 60x30, centered vertically
+	for _, p := range []image.Point{{30, 5}, {30, 54}} {
+		if g := gray(img, p.X, p.Y); g < 200 {
+			t.Errorf("padding at %v is %d, want white", p, g)
+		}
+	}
+	for _, p := range []image.Point{{2, 30}, {30, 17}, {57, 42}} {
+		if g := gray(img, p.X, p.Y); g > 50 {
+			t.Errorf("image at %v is %d, want black", p, g)
+		}
+	}
+}
+
+func TestUseAlphaChannel(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	hidden, err := ioutil.ReadFile("test/fixtures/alpha/42.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// flattened against its own color, the text is invisible
+	if _, err := ocr.ParseImage(hidden, baiduocr.SetPNGBackgroundColorRGBA(128, 128, 128, 255)); err != nil {
+		t.Fatal(err)
+	}
+	if text, background := gray(submitted(), 36, 12), gray(submitted(), 2, 2); absDiff(text, background) > 5 {
+		t.Errorf("text %d and background %d differ", text, background)
+	}
+	if _, err := ocr.ParseImage(hidden, baiduocr.UseAlphaChannel(), baiduocr.SetPNGBackgroundColorRGBA(128, 128, 128, 255)); err != nil {
+		t.Fatal(err)
+	}
+	if text, background := gray(submitted(), 36, 12), gray(submitted(), 2, 2); text > 30 || background < 200 {
+		t.Errorf("text %d, background %d, want black on white", text, background)
+	}
+}
+
+func TestSetMedianFilter(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	clean, err := ioutil.ReadFile("test/fixtures/simple-captcha/3560.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noisy, err := ioutil.ReadFile("test/fixtures/noisy/3560.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	white := baiduocr.SetPNGBackgroundColor(color.White)
+	// mean difference in gray level from the image without noise
+	diff := func(data []byte, options ...baiduocr.BaiduOCROption) float64 {
+		if _, err := ocr.ParseImage(clean, white, baiduocr.SetMedianFilter(1)); err != nil {
+			t.Fatal(err)
+		}
+		want := submitted()
+		if _, err := ocr.ParseImage(data, append(options, white)...); err != nil {
+			t.Fatal(err)
+		}
+		got := submitted()
+		var sum int
+		bounds := got.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				sum += int(absDiff(gray(got, x, y), gray(want, x, y)))
+			}
+		}
+		return float64(sum) / float64(bounds.Dx()*bounds.Dy())
+	}
+	filtered, unfiltered := diff(noisy, baiduocr.SetMedianFilter(1)), diff(noisy)
+	if filtered > unfiltered/4 {
+		t.Errorf("mean difference is %.1f filtered, %.1f unfiltered", filtered, unfiltered)
+	}
+	if _, err := ocr.ParseImage(noisy, baiduocr.SetMedianFilter(0)); err == nil {
+		t.Error("want error for radius 0")
+	}
+}
+
+func TestSetColorFilter(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	captcha, err := ioutil.ReadFile("test/fixtures/multicolor/42-red.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ocr.ParseImage(captcha, baiduocr.SetColorFilter(color.RGBA{200, 30, 30, 255}, 0.2)); err != nil {
+		t.Fatal(err)
+	}
+	img := submitted()
+	// red 4 and 2 are kept, blue 1 and 7 are removed
+	for _, p := range []image.Point{{9, 33}, {81, 45}} {
+		if r, g, _, _ := img.At(p.X, p.Y).RGBA(); r>>8 < 150 || g>>8 > 80 {
+			t.Errorf("pixel at %v is not red", p)
+		}
+	}
+	for _, p := range []image.Point{{57, 21}, {117, 9}} {
+		if g := gray(img, p.X, p.Y); g < 240 {
+			t.Errorf("pixel at %v is %d, want white", p, g)
+		}
+	}
+}
+
+func TestSetColorFilters(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	captcha, err := ioutil.ReadFile("test/fixtures/multicolor/42-red-green.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []color.Color{color.RGBA{200, 30, 30, 255}, color.RGBA{30, 160, 50, 255}}
+	if _, err := ocr.ParseImage(captcha, baiduocr.SetColorFilters(targets, 0.2)); err != nil {
+		t.Fatal(err)
+	}
+	img := submitted()
+	// red 4 and green 2 are kept, blue 1 and gray 7 are removed
+	if r, g, _, _ := img.At(9, 33).RGBA(); r>>8 < 150 || g>>8 > 80 {
+		t.Error("red 4 is removed")
+	}
+	if r, g, _, _ := img.At(81, 45).RGBA(); r>>8 > 80 || g>>8 < 120 {
+		t.Error("green 2 is removed")
+	}
+	for _, p := range []image.Point{{57, 21}, {117, 9}} {
+		if g := gray(img, p.X, p.Y); g < 240 {
+			t.Errorf("pixel at %v is %d, want white", p, g)
+		}
+	}
+}
+
+func TestNormalizeToOrientation(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	// 100x50, black on the left half
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(src, src.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(0, 0, 50, 50), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	if _, err := ocr.ParseImage(encodePNG(t, src), baiduocr.NormalizeToOrientation(baiduocr.OrientationLandscape)); err != nil {
+		t.Fatal(err)
+	}
+	if size := submitted().Bounds().Size(); size != image.Pt(100, 50) {
+		t.Errorf("size = %v, want landscape kept as is", size)
+	}
+
+	if _, err := ocr.ParseImage(encodePNG(t, src), baiduocr.NormalizeToOrientation(baiduocr.OrientationPortrait)); err != nil {
+		t.Fatal(err)
+	}
+	img := submitted()
+	if size := img.Bounds().Size(); size != image.Pt(50, 100) {
+		t.Fatalf("size = %v, want 50x100", size)
+	}
+	// rotated clockwise, the left half is at the top
+	if top, bottom := gray(img, 25, 20), gray(img, 25, 80); top > 50 || bottom < 200 {
+		t.Errorf("top %d, bottom %d, want black on top", top, bottom)
+	}
+
+	if _, err := ocr.ParseImage(encodePNG(t, src), baiduocr.NormalizeToOrientation(0)); err == nil {
+		t.Error("got no error for unknown orientation")
+	}
+}
+
+func TestSetCrop(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	// 100x50, black in the bottom right 20x10 corner
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(src, src.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(80, 40, 100, 50), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	for _, option := range []baiduocr.BaiduOCROption{
+		baiduocr.SetCropFractional(0.8, 0.8, 1, 1),
+		baiduocr.SetCropRect(image.Rect(80, 40, 120, 60)),
+	} {
+		if _, err := ocr.ParseImage(encodePNG(t, src), option); err != nil {
+			t.Fatal(err)
+		}
+		img := submitted()
+		if size := img.Bounds().Size(); size != image.Pt(20, 10) {
+			t.Fatalf("size = %v, want 20x10", size)
+		}
+		if g := gray(img, img.Bounds().Min.X+10, img.Bounds().Min.Y+5); g > 50 {
+			t.Errorf("cropped image is %d, want black", g)
+		}
+	}
+
+	for _, option := range []baiduocr.BaiduOCROption{
+		baiduocr.SetCropFractional(0.5, 0, 0.2, 1),
+		baiduocr.SetCropFractional(0, 0, 1, 1.5),
+		baiduocr.SetCropFractional(-0.1, 0, 1, 1),
+		baiduocr.SetCropFractional(0, 0, 0.001, 1),
+		baiduocr.SetCropRect(image.Rect(100, 0, 120, 50)),
+	} {
+		if _, err := ocr.ParseImage(encodePNG(t, src), option); err == nil {
+			t.Error("got no error for invalid crop")
+		}
+	}
+}
+
+func TestPreprocessParallelism(t *testing.T) {
+	ocr, submitted := submittedImage(t)
+	src := image.NewRGBA(image.Rect(0, 0, 101, 37))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 37 % 251)
+	}
+	imageBytes := encodePNG(t, src)
+	options := []baiduocr.BaiduOCROption{
+		baiduocr.SetColorFilter(color.RGBA{128, 128, 128, 255}, 0.4),
+		baiduocr.SetMedianFilter(1),
+		baiduocr.SetFixedCanvas(160, 60, color.White),
+	}
+	var images []image.Image
+	for _, parallelism := range []int{1, 7, 100} {
+		ocr.PreprocessParallelism = parallelism
+		if _, err := ocr.ParseImage(imageBytes, options...); err != nil {
+			t.Fatal(err)
+		}
+		images = append(images, submitted())
+	}
+	for _, img := range images[1:] {
+		bounds := img.Bounds()
+		if bounds != images[0].Bounds() {
+			t.Fatalf("bounds %v, want %v", bounds, images[0].Bounds())
+		}
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if img.At(x, y) != images[0].At(x, y) {
+					t.Fatalf("pixel (%d,%d) differs from the sequential one", x, y)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package baiduocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EndpointConfig is an endpoint of ParseRace: OCR with its apistore
+// endpoint, or, if Aip is set, Aip with the AIP endpoint named AipEndpoint,
+// such as "general_basic".
+type EndpointConfig struct {
+	OCR         OCR
+	Aip         *AipOCR
+	AipEndpoint string
+}
+
+// Read text from JPEG/PNG image with all the endpoints at the same time,
+// returning the results of the first one to succeed. The calls to the other
+// endpoints are then cancelled through their context, but cancelling does
+// not refund them: every endpoint is called, and each counts against the
+// quota of its key even if its results are thrown away, so a race of two
+// endpoints costs twice as much quota as a single call. If all of them fail,
+// the error joins their errors, each prefixed with its endpoint. The context
+// set by SetContext cancels all the calls.
+func ParseRace(imageBytes []byte, endpoints []EndpointConfig, options ...BaiduOCROption) (results []string, err error) {
+	if len(endpoints) == 0 {
+		err = errors.New("no endpoint to race")
+		return
+	}
+	parent := newOptions(options).context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	options = append(options[:len(options):len(options)], SetContext(ctx))
+
+	type outcome struct {
+		index   int
+		results []string
+		err     error
+	}
+	outcomes := make(chan outcome, len(endpoints))
+	for i, endpoint := range endpoints {
+		go func(i int, endpoint EndpointConfig) {
+			var results []string
+			var err error
+			if endpoint.Aip != nil {
+				var words []Word
+				words, _, err = endpoint.Aip.ParseDetailed(endpoint.AipEndpoint, imageBytes, options...)
+				results = wordTexts(words)
+			} else {
+				results, err = endpoint.OCR.ParseImage(imageBytes, options...)
+			}
+			outcomes <- outcome{i, results, err}
+		}(i, endpoint)
+	}
+	errs := make([]error, len(endpoints))
+	for range endpoints {
+		outcome := <-outcomes
+		if outcome.err == nil {
+			results = outcome.results
+			return
+		}
+		errs[outcome.index] = fmt.Errorf("%s: %w", endpoints[outcome.index].name(), outcome.err)
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+func (endpoint EndpointConfig) name() string {
+	if endpoint.Aip != nil {
+		return endpoint.AipEndpoint
+	}
+	path := endpoint.OCR.APIPath
+	if path == "" {
+		path = _API_PATH
+	}
+	return path
+}
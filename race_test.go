@@ -0,0 +1,58 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseRace(t *testing.T) {
+	cancelled := make(chan bool, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices the client going away once the body is read
+		r.ParseForm()
+		select {
+		case <-r.Context().Done():
+			cancelled <- true
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return wordsResult("fast")
+	})
+
+	results, err := baiduocr.ParseRace(fakeJPEG, []baiduocr.EndpointConfig{
+		{OCR: baiduocr.OCR{APIKey: "test-api-key", APIPath: slow.URL}},
+		{Aip: aip, AipEndpoint: "general_basic"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(results) != "[fast]" {
+		t.Errorf("got %v", results)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the slow call was not cancelled")
+	}
+
+	noText := newTestServer(t, func(r *http.Request) []string { return nil })
+	rejecting, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		return map[string]interface{}{"error_code": 216201, "error_msg": "image format error"}
+	})
+	_, err = baiduocr.ParseRace(fakeJPEG, []baiduocr.EndpointConfig{
+		{OCR: noText},
+		{Aip: rejecting, AipEndpoint: "general_basic"},
+	})
+	if !errors.Is(err, baiduocr.ErrNoText) || !strings.Contains(err.Error(), "general_basic: BaiduOCR error 216201") {
+		t.Errorf("got %v, want errors of both endpoints", err)
+	}
+}
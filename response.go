@@ -0,0 +1,151 @@
+package baiduocr
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrServiceUnavailable is returned when Baidu OCR answers with an HTML page
+// instead of JSON, as it does with a 200 status during maintenance. The
+// returned error wraps it with the status and the start of the page, test
+// for it with errors.Is. Such responses are retried like server errors.
+var ErrServiceUnavailable = errors.New("BaiduOCR service unavailable")
+
+const _HTML_SNIPPET_LENGTH = 200
+
+// responseShape holds the fields telling the shapes of responses apart: the
+// retData of the apistore endpoint and the words_result of AIP endpoints.
+type responseShape struct {
+	WordsResult json.RawMessage `json:"words_result"`
+	ErrorCode   json.RawMessage `json:"error_code"`
+}
+
+// parseResponse returns the words of a response to OCR, which is in the shape
+// of the apistore endpoint, or of an AIP endpoint if APIPath is a gateway to
+// one, and fills meta with what the response has.
+func parseResponse(body []byte, meta *ResultMeta) (words []Word, err error) {
+	var shape responseShape
+	err = json.Unmarshal(body, &shape)
+	if err != nil {
+		return
+	}
+	if shape.WordsResult != nil || shape.ErrorCode != nil {
+		var ret aipOCRRet
+		err = json.Unmarshal(body, &ret)
+		if err != nil {
+			return
+		}
+		words, err = ret.result(meta)
+		return
+	}
+	var ret baiduOCRRet
+	err = json.Unmarshal(body, &ret)
+	if err != nil {
+		return
+	}
+	words, err = ret.result()
+	return
+}
+
+// checkHTML returns ErrServiceUnavailable if the response is an HTML page,
+// which has an HTML content type or a body starting with "<". Other content
+// types are read as JSON, as gateways often send JSON as text/plain.
+// Otherwise it returns the body to read the response from instead of
+// resp.Body.
+func checkHTML(resp *http.Response) (body io.ReadCloser, err error) {
+	reader := bufio.NewReaderSize(resp.Body, _HTML_SNIPPET_LENGTH)
+	body = struct {
+		io.Reader
+		io.Closer
+	}{reader, resp.Body}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("content-type"))
+	html := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	for n := 1; !html; n++ {
+		peeked, err := reader.Peek(n)
+		if err != nil {
+			break
+		}
+		if c := peeked[n-1]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			html = c == '<'
+			break
+		}
+	}
+	if !html {
+		return
+	}
+	snippet, _ := ioutil.ReadAll(io.LimitReader(reader, _HTML_SNIPPET_LENGTH))
+	body.Close()
+	err = fmt.Errorf("%w: %s: %q", ErrServiceUnavailable, resp.Status, strings.Join(strings.Fields(string(snippet)), " "))
+	return
+}
+
+func (ret baiduOCRRet) result() (words []Word, err error) {
+	if ret.ErrNum >= _APISTORE_ERRORS {
+		err = newOCRError(ret.ErrNum, ret.ErrMsg)
+		return
+	}
+	if len(ret.RetData) == 0 {
+		err = noTextError(ret.ErrMsg)
+		return
+	}
+	for _, data := range ret.RetData {
+		words = append(words, data.word())
+	}
+	return
+}
+
+func (data baiduOCRWord) word() Word {
+	left, _ := strconv.Atoi(data.Rect.Left)
+	top, _ := strconv.Atoi(data.Rect.Top)
+	width, _ := strconv.Atoi(data.Rect.Width)
+	height, _ := strconv.Atoi(data.Rect.Height)
+	rect := image.Rect(left, top, left+width, top+height)
+	return Word{
+		Text: data.Word,
+		Rect: rect,
+		Quad: rectQuad(rect),
+	}
+}
+
+func (ret aipOCRRet) result(meta *ResultMeta) (words []Word, err error) {
+	if ret.ErrorCode != 0 {
+		err = newOCRError(ret.ErrorCode, ret.ErrorMsg)
+		return
+	}
+	meta.DetectedLanguage = ret.language()
+	for _, paragraph := range ret.ParagraphsResult {
+		meta.Lines = append(meta.Lines, paragraph.WordsResultIdx)
+	}
+	if len(ret.WordsResult) == 0 {
+		err = noTextError("")
+		return
+	}
+	for _, data := range ret.WordsResult {
+		word := Word{
+			Text: data.Words,
+			Rect: data.Location.rect(),
+		}
+		if len(data.VertexesLocation) == 4 {
+			copy(word.Quad[:], data.VertexesLocation)
+		} else {
+			word.Quad = rectQuad(word.Rect)
+		}
+		for _, char := range data.Chars {
+			word.Chars = append(word.Chars, Char{char.Char, char.Location.rect()})
+		}
+		if data.Probability != nil {
+			word.Confidence, word.HasConfidence = data.Probability.Average, true
+		}
+		words = append(words, word)
+	}
+	return
+}
@@ -0,0 +1,125 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestWordsResultResponse(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+
+	response = `{"words_result":[{"words":"gateway","location":{"left":1,"top":2,"width":3,"height":4}}],"words_result_num":1}`
+	words, _, err := ocr.ParseDetailed(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || words[0].Text != "gateway" || words[0].Rect != image.Rect(1, 2, 4, 6) {
+		t.Errorf("got %+v", words)
+	}
+
+	response = `{"error_code":216201,"error_msg":"image format error"}`
+	if _, err := ocr.ParseJPEG(fakeJPEG); err == nil || !strings.Contains(err.Error(), "216201") {
+		t.Errorf("got %v, want error of the endpoint", err)
+	}
+
+	response = `{"words_result":[],"words_result_num":0}`
+	if _, err := ocr.ParseJPEG(fakeJPEG); !errors.Is(err, baiduocr.ErrNoText) {
+		t.Errorf("got %v, want no text error", err)
+	}
+}
+
+func TestErrNoText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errNum":0,"errMsg":"image too small","retData":[]}`)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	if !errors.Is(err, baiduocr.ErrNoText) {
+		t.Fatalf("got %v, want ErrNoText", err)
+	}
+	if !strings.Contains(err.Error(), "image too small") {
+		t.Errorf("error %q does not have the reason", err)
+	}
+}
+
+func TestServiceUnavailable(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// maintenance pages come with a 200 status
+			fmt.Fprint(w, "\n<html><head><title>系统维护中</title></head>\n<body>Under maintenance</body></html>")
+			return
+		}
+		fmt.Fprint(w, `{"retData":[{"word":"ok"}]}`)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	if !errors.Is(err, baiduocr.ErrServiceUnavailable) {
+		t.Fatalf("got %v, want ErrServiceUnavailable", err)
+	}
+	if !strings.Contains(err.Error(), "200 OK") || !strings.Contains(err.Error(), "<title>系统维护中</title>") {
+		t.Errorf("error %q does not have the status and the page", err)
+	}
+
+	calls = 0
+	ocr.MaxRetries, ocr.RetryBackoff = 1, time.Millisecond
+	if results, err := ocr.ParseJPEG(fakeJPEG); err != nil || fmt.Sprint(results) != "[ok]" {
+		t.Errorf("got %v, %v, want the page to be retried", results, err)
+	}
+}
+
+func TestResponseUTF8(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "\xef\xbb\xbf"+`{"retData":[{"word":"日本"},{"word":"中`+"\xe6"+`文"}]}`)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+
+	results, err := ocr.ParseJPEG(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(results) != "[日本 中�文]" {
+		t.Errorf("got %q, want the BOM stripped and the invalid byte replaced", results)
+	}
+
+	var streamed []string
+	for result := range ocr.ParseStream(fakeJPEG) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		streamed = append(streamed, result.Word.Text)
+	}
+	if fmt.Sprint(streamed) != fmt.Sprint(results) {
+		t.Errorf("streamed %q, want %q", streamed, results)
+	}
+
+	_, err = ocr.ParseJPEG(fakeJPEG, baiduocr.RejectInvalidUTF8())
+	if !errors.Is(err, baiduocr.ErrInvalidUTF8) || !strings.Contains(err.Error(), "at byte 42") {
+		t.Errorf("got %v, want ErrInvalidUTF8 at byte 42", err)
+	}
+	var streamErr error
+	for result := range ocr.ParseStream(fakeJPEG, baiduocr.RejectInvalidUTF8()) {
+		streamErr = result.Err
+	}
+	if !errors.Is(streamErr, baiduocr.ErrInvalidUTF8) {
+		t.Errorf("got %v, want ErrInvalidUTF8 from the stream", streamErr)
+	}
+}
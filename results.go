@@ -0,0 +1,553 @@
+package baiduocr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var digitsRegexp = regexp.MustCompile("[0-9]+")
+
+const _AUTO_CLEAN_MIN_WORDS = 8
+
+// ErrTooFewResults is returned when fewer results than set by SetMinResults
+// are recognized. The returned error wraps it with the counts, test for it
+// with errors.Is.
+var ErrTooFewResults = errors.New("too few results")
+
+// Option to make ParseDigits return all the digits found as a single result,
+// instead of one result for each run of digits.
+func JoinDigits() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.joinDigits = true }}
+}
+
+// Option to replace the text of results exactly equal to a key of
+// corrections by its value, such as words Baidu consistently misrecognizes
+// in a logo font. See SetCorrectionFunc for the order corrections are
+// applied in.
+func SetCorrections(corrections map[string]string) BaiduOCROption {
+	copied := make(map[string]string, len(corrections))
+	for from, to := range corrections {
+		copied[from] = to
+	}
+	return SetCorrectionFunc(func(text string) string {
+		if corrected, ok := copied[text]; ok {
+			return corrected
+		}
+		return text
+	})
+}
+
+// Option to replace the text of each result by what correct returns for it.
+// Corrections, including those of SetCorrections, are applied in the order
+// they are given, to each result as recognized by Baidu and before the other
+// result options, so that CleanResults, SetMinResults, DetectScript and the
+// joining of results such as by SingleLineMode and JoinDigits see the
+// corrected text. A result corrected to empty text is dropped by
+// CleanResults. Word.Chars are left as recognized.
+func SetCorrectionFunc(correct func(string) string) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) {
+		option.corrections = append(option.corrections, correct)
+	}}
+}
+
+// Option to clean up the results as a whole: "\r\n" in the text of a result
+// is normalized to "\n", then results that are empty or contain only
+// whitespace are dropped. If no result remains, the call fails as if Baidu
+// recognized no text. The text of the remaining results is not trimmed.
+func CleanResults() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.cleanResults = true }}
+}
+
+// How SetTrimMode trims the text of results.
+type TrimMode int
+
+// Values of TrimMode.
+const (
+	// Keep the text as it is, the default
+	TrimNone TrimMode = iota
+	// Trim whitespace at both ends
+	TrimBoth
+	// Trim whitespace at the start only
+	TrimLeading
+	// Trim whitespace at the end only
+	TrimTrailing
+)
+
+// Option to trim the whitespace, as defined by Unicode, around the text of
+// each result, at the ends chosen by mode, such as for captcha answers
+// compared exactly. Trimming follows the corrections and CleanResults, so a
+// result of only whitespace is dropped with CleanResults and kept with empty
+// text without it. The default is TrimNone, keeping the text as recognized.
+func SetTrimMode(mode TrimMode) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.trimMode = mode }}
+}
+
+// How SetCaseMode changes the case of results.
+type CaseMode int
+
+// Values of CaseMode.
+const (
+	// Keep the case as recognized, the default
+	CaseUnchanged CaseMode = iota
+	// Map letters to upper case
+	CaseUpper
+	// Map letters to lower case
+	CaseLower
+)
+
+// Option to map the letters of each result, and of its Chars, to the case of
+// mode, such as for captchas compared without case, as Baidu often gets the
+// case of stylized letters wrong. The case follows the corrections and
+// SetTrimMode. Letters without case, such as Chinese, are unchanged. The
+// default is CaseUnchanged.
+func SetCaseMode(mode CaseMode) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.caseMode = mode }}
+}
+
+// Option to cap the results at n characters in total, counted over the
+// concatenation of their texts without separators, such as to fit a column
+// of a database. The result crossing the limit is cut at a character, along
+// with its Chars, and the results after it are dropped; Truncated of
+// ResultMeta is set by the ParseDetailed methods. The minimum of
+// SetMinResults applies before truncation. Zero, the default, is unlimited.
+func SetMaxTotalChars(n int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.maxTotalChars = n }}
+}
+
+// Option to drop recognized words whose bounding box is smaller than area
+// square pixels, such as stray marks and punctuation in screenshots. Words
+// without a bounding box are kept, so it has no effect when the endpoint does
+// not locate text, like the AIP *_basic endpoints.
+func SetMinBoxArea(area int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.minBoxArea = area }}
+}
+
+// Option to fail with ErrTooFewResults if fewer than n results are
+// recognized, such as fewer characters than a fixed-length captcha has. The
+// results are counted after the other result options, such as CleanResults
+// and SetMinBoxArea, are applied. If no text is recognized at all, the call
+// fails as usual instead.
+func SetMinResults(n int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.minResults = n }}
+}
+
+// Option to keep at most the first n results, after the other result options
+// such as SortByConfidence, so that with SortByConfidence, SetMaxResults(1)
+// gives the most likely result. Zero, the default, keeps all.
+func SetMaxResults(n int) BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.maxResults = n }}
+}
+
+// Option to recognize the image as a single line of text, such as a
+// captcha, whose words Baidu would otherwise sometimes split into pieces.
+// OCR sends detecttype=Recognize instead of LocateRecognize, so that the
+// apistore endpoint recognizes the image as a whole without locating text
+// first; the AIP endpoints have no such parameter. Whatever the endpoint, the
+// words recognized are then joined into a single result, from left to right
+// by their bounding boxes, whose box covers them all. As there is only one
+// result, SetMinResults counts the characters of its text instead.
+func SingleLineMode() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.singleLine = true }}
+}
+
+// Option to drop noise adaptively, such as the specks and icons of messy
+// screenshots recognized as stray characters, when the results look noisy.
+// After the other filtering options, it looks at the words that have a
+// bounding box, if there are at least 8 of them:
+//
+//   - a word is tiny if its box is smaller than a quarter of the median box
+//     area, and if at least a quarter of the words are tiny, the tiny words
+//     are dropped;
+//   - a word has low confidence if Baidu reports a confidence below half of
+//     the median confidence of the words reporting one (see
+//     RequestConfidence), and if at least a quarter of those words have low
+//     confidence, these words are dropped.
+//
+// Both are decided on the same words, before dropping any. Results of clean
+// images, with few outliers, are left as they are, and words without a
+// bounding box are always kept.
+func AutoCleanNoise() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.autoCleanNoise = true }}
+}
+
+// Option to sort the words in a deterministic order, so that the same image
+// always gives the same results whatever order Baidu returns them in, as
+// needed by golden-file tests. Words are sorted from top to bottom by their
+// bounding boxes, then from left to right, then by the bottom and right of
+// the boxes, and words with the same box by text. Words without a bounding
+// box, as returned by endpoints not locating text, come last sorted by text.
+// It is not an order of reading, use ParseLines for that.
+func StableOrder() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.stableOrder = true }}
+}
+
+// Option to sort the words from the most to the least confident, such as to
+// pick the most likely of several detections with SetMaxResults(1). It needs
+// the confidence of RequestConfidence; words without one come last. The sort
+// is stable: words of equal confidence, or all of them if none has a
+// confidence, keep the order of Baidu, or of StableOrder if also used.
+func SortByConfidence() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.sortByConfidence = true }}
+}
+
+// Values of Word.Script, see DetectScript.
+const (
+	ScriptLatin  = "Latin"
+	ScriptHan    = "Han"
+	ScriptKana   = "Kana"
+	ScriptHangul = "Hangul"
+)
+
+// Option to report the script of each recognized word in Word.Script: one
+// of ScriptLatin, ScriptHan, ScriptKana (hiragana and katakana) and
+// ScriptHangul, whichever most letters of the word are written in, or empty
+// if the word has no letter of these scripts, such as digits only. No Baidu
+// endpoint reports scripts, so they are derived from the characters of the
+// recognized text, whatever the endpoint. The AIP endpoints accepting
+// detect_language (general_basic, general, accurate_basic and accurate) are
+// also asked to detect the language, so that Japanese kanji are reported as
+// Han but ResultMeta.DetectedLanguage tells Japanese text apart.
+func DetectScript() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.detectScript = true }}
+}
+
+var scripts = []struct {
+	name   string
+	tables []*unicode.RangeTable
+}{
+	{ScriptLatin, []*unicode.RangeTable{unicode.Latin}},
+	{ScriptHan, []*unicode.RangeTable{unicode.Han}},
+	{ScriptKana, []*unicode.RangeTable{unicode.Hiragana, unicode.Katakana}},
+	{ScriptHangul, []*unicode.RangeTable{unicode.Hangul}},
+}
+
+// textScript returns the script most letters of text are written in.
+func textScript(text string) (script string) {
+	counts := make([]int, len(scripts))
+	for _, r := range text {
+		for i, s := range scripts {
+			if unicode.In(r, s.tables...) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	best := 0
+	for i, count := range counts {
+		if count > best {
+			script, best = scripts[i].name, count
+		}
+	}
+	return
+}
+
+// postprocess applies the result options to the words recognized by Baidu
+// and sets their NormalizedRect. The indexes of meta.Lines are updated to
+// the words returned, and meta.RawWordCount is set to the words given.
+func (opts baiduOCROption) postprocess(words []Word, meta *ResultMeta) ([]Word, error) {
+	meta.RawWordCount = len(words)
+	var kept []Word
+	var indexes []int
+	for i, word := range words {
+		if word, ok := opts.keep(word); ok {
+			kept = append(kept, word)
+			indexes = append(indexes, i)
+		}
+	}
+	if opts.autoCleanNoise {
+		noisy := noisyWords(kept)
+		var cleaned []Word
+		var cleanedIndexes []int
+		for i, word := range kept {
+			if !noisy[i] {
+				cleaned = append(cleaned, word)
+				cleanedIndexes = append(cleanedIndexes, indexes[i])
+			}
+		}
+		kept, indexes = cleaned, cleanedIndexes
+	}
+	if opts.stableOrder {
+		sort.Sort(stableWords{kept, indexes})
+	}
+	if opts.sortByConfidence {
+		sort.Stable(confidentWords{stableWords{kept, indexes}})
+	}
+	if len(kept) == 0 {
+		return nil, opts.formatted(noTextError(""))
+	}
+	if opts.singleLine {
+		line := opts.joinLine(kept)
+		if count := utf8.RuneCountInString(line.Text); count < opts.minResults {
+			return nil, fmt.Errorf("%w: got %d characters, want at least %d", ErrTooFewResults, count, opts.minResults)
+		}
+		if meta.Lines != nil {
+			meta.Lines = [][]int{{0}}
+		}
+		var lines []Word
+		lines, meta.Truncated = truncateWords([]Word{line}, opts.maxTotalChars)
+		line = lines[0]
+		line.NormalizedRect = normalizeRect(line.Rect, meta.ImageSize)
+		return []Word{line}, nil
+	}
+	if len(kept) < opts.minResults {
+		return nil, fmt.Errorf("%w: got %d, want at least %d", ErrTooFewResults, len(kept), opts.minResults)
+	}
+	if opts.maxResults > 0 && len(kept) > opts.maxResults {
+		kept = kept[:opts.maxResults]
+	}
+	kept, meta.Truncated = truncateWords(kept, opts.maxTotalChars)
+	meta.Lines = reindexLines(meta.Lines, indexes[:len(kept)])
+	for i := range kept {
+		kept[i].NormalizedRect = normalizeRect(kept[i].Rect, meta.ImageSize)
+	}
+	return kept, nil
+}
+
+// truncateWords cuts the words to max characters in total, see
+// SetMaxTotalChars, returning whether any was cut or dropped.
+func truncateWords(words []Word, max int) (truncated []Word, cut bool) {
+	if max <= 0 {
+		return words, false
+	}
+	total := 0
+	for i, word := range words {
+		count := utf8.RuneCountInString(word.Text)
+		if total+count <= max {
+			total += count
+			continue
+		}
+		truncated = append(truncated, words[:i]...)
+		if left := max - total; left > 0 {
+			word.Text = string([]rune(word.Text)[:left])
+			if len(word.Chars) > left {
+				word.Chars = word.Chars[:left:left]
+			}
+			truncated = append(truncated, word)
+		}
+		return truncated, true
+	}
+	return words, false
+}
+
+// keep returns the word as corrected, cleaned by CleanResults, trimmed and
+// cased, or false if it is to be dropped.
+func (opts baiduOCROption) keep(word Word) (Word, bool) {
+	for _, correct := range opts.corrections {
+		word.Text = correct(word.Text)
+	}
+	if !utf8.ValidString(word.Text) {
+		// the response is valid, but a correction may have cut a rune
+		word.Text = strings.ToValidUTF8(word.Text, string(utf8.RuneError))
+	}
+	if opts.minBoxArea > 0 && !word.Rect.Empty() && word.Rect.Dx()*word.Rect.Dy() < opts.minBoxArea {
+		return word, false
+	}
+	if opts.cleanResults {
+		word.Text = strings.Replace(word.Text, "\r\n", "\n", -1)
+		if strings.TrimSpace(word.Text) == "" {
+			return word, false
+		}
+	}
+	switch opts.trimMode {
+	case TrimBoth:
+		word.Text = strings.TrimSpace(word.Text)
+	case TrimLeading:
+		word.Text = strings.TrimLeftFunc(word.Text, unicode.IsSpace)
+	case TrimTrailing:
+		word.Text = strings.TrimRightFunc(word.Text, unicode.IsSpace)
+	}
+	if toCase := opts.caseMode.mapping(); toCase != nil {
+		word.Text = toCase(word.Text)
+		if word.Chars != nil {
+			word.Chars = append([]Char(nil), word.Chars...)
+			for i := range word.Chars {
+				word.Chars[i].Text = toCase(word.Chars[i].Text)
+			}
+		}
+	}
+	if opts.detectScript {
+		word.Script = textScript(word.Text)
+	}
+	return word, true
+}
+
+// mapping returns the function changing the case of a text, or nil if the
+// case is unchanged.
+func (mode CaseMode) mapping() func(string) string {
+	switch mode {
+	case CaseUpper:
+		return strings.ToUpper
+	case CaseLower:
+		return strings.ToLower
+	}
+	return nil
+}
+
+// noisyWords reports which words AutoCleanNoise drops.
+func noisyWords(words []Word) []bool {
+	noisy := make([]bool, len(words))
+	var areas, confidences []float64
+	for _, word := range words {
+		if word.Rect.Empty() {
+			continue
+		}
+		areas = append(areas, float64(word.Rect.Dx()*word.Rect.Dy()))
+		if word.HasConfidence {
+			confidences = append(confidences, word.Confidence)
+		}
+	}
+	if len(areas) < _AUTO_CLEAN_MIN_WORDS {
+		return noisy
+	}
+	minArea, minConfidence := median(areas)/4, median(confidences)/2
+	var tiny, unsure []int
+	for i, word := range words {
+		if word.Rect.Empty() {
+			continue
+		}
+		if float64(word.Rect.Dx()*word.Rect.Dy()) < minArea {
+			tiny = append(tiny, i)
+		}
+		if word.HasConfidence && word.Confidence < minConfidence {
+			unsure = append(unsure, i)
+		}
+	}
+	if len(tiny)*4 >= len(areas) {
+		for _, i := range tiny {
+			noisy[i] = true
+		}
+	}
+	if len(confidences) > 0 && len(unsure)*4 >= len(confidences) {
+		for _, i := range unsure {
+			noisy[i] = true
+		}
+	}
+	return noisy
+}
+
+// joinLine joins the words into one for SingleLineMode. Words without a
+// bounding box keep their place among each other, after those with one.
+func (opts baiduOCROption) joinLine(words []Word) (line Word) {
+	words = append([]Word(nil), words...)
+	sort.SliceStable(words, func(i, j int) bool {
+		a, b := words[i].Rect, words[j].Rect
+		if a.Empty() || b.Empty() {
+			return b.Empty() && !a.Empty()
+		}
+		return a.Min.X < b.Min.X
+	})
+	var texts []string
+	line.HasConfidence = true
+	for i, word := range words {
+		texts = append(texts, word.Text)
+		line.Rect = line.Rect.Union(word.Rect)
+		line.Chars = append(line.Chars, word.Chars...)
+		// the line is only as confident as its least confident word
+		if i == 0 || word.Confidence < line.Confidence {
+			line.Confidence = word.Confidence
+		}
+		line.HasConfidence = line.HasConfidence && word.HasConfidence
+	}
+	line.Text = strings.Join(texts, "")
+	if !line.HasConfidence {
+		line.Confidence = 0
+	}
+	line.Quad = rectQuad(line.Rect)
+	if opts.detectScript {
+		line.Script = textScript(line.Text)
+	}
+	return
+}
+
+// reindexLines returns the lines with their indexes into the words replaced
+// by the indexes of the same words in kept, where kept[i] is the original
+// index of the i-th word kept. Indexes of words not kept are dropped.
+func reindexLines(lines [][]int, kept []int) (reindexed [][]int) {
+	if lines == nil {
+		return nil
+	}
+	newIndexes := map[int]int{}
+	for i, original := range kept {
+		newIndexes[original] = i
+	}
+	reindexed = [][]int{}
+	for _, line := range lines {
+		var group []int
+		for _, original := range line {
+			if i, ok := newIndexes[original]; ok {
+				group = append(group, i)
+			}
+		}
+		if len(group) > 0 {
+			reindexed = append(reindexed, group)
+		}
+	}
+	return
+}
+
+// stableWords sorts words for StableOrder, along with their indexes.
+type stableWords struct {
+	words   []Word
+	indexes []int
+}
+
+func (s stableWords) Len() int { return len(s.words) }
+
+func (s stableWords) Swap(i, j int) {
+	s.words[i], s.words[j] = s.words[j], s.words[i]
+	s.indexes[i], s.indexes[j] = s.indexes[j], s.indexes[i]
+}
+
+func (s stableWords) Less(i, j int) bool {
+	a, b := s.words[i], s.words[j]
+	if a.Rect.Empty() != b.Rect.Empty() {
+		return b.Rect.Empty()
+	}
+	if !a.Rect.Empty() {
+		for _, d := range []int{
+			a.Rect.Min.Y - b.Rect.Min.Y, a.Rect.Min.X - b.Rect.Min.X,
+			a.Rect.Max.Y - b.Rect.Max.Y, a.Rect.Max.X - b.Rect.Max.X,
+		} {
+			if d != 0 {
+				return d < 0
+			}
+		}
+	}
+	return a.Text < b.Text
+}
+
+// confidentWords sorts words for SortByConfidence, along with their indexes.
+type confidentWords struct {
+	stableWords
+}
+
+func (s confidentWords) Less(i, j int) bool {
+	a, b := s.words[i], s.words[j]
+	if a.HasConfidence != b.HasConfidence {
+		return a.HasConfidence
+	}
+	return a.Confidence > b.Confidence
+}
+
+// extractDigits returns the runs of digits in texts, or all of them in one
+// string if join is true.
+func extractDigits(texts []string, join bool) (digits []string) {
+	for _, text := range texts {
+		text = strings.Map(func(r rune) rune {
+			if r >= '０' && r <= '９' {
+				return '0' + r - '０'
+			}
+			return r
+		}, text)
+		digits = append(digits, digitsRegexp.FindAllString(text, -1)...)
+	}
+	if join && len(digits) > 0 {
+		digits = []string{strings.Join(digits, "")}
+	}
+	return
+}
@@ -0,0 +1,392 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestCleanResults(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"", "first\r\nline", " \t", "second", "\r\n"}
+	})
+	results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.CleanResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0] != "first\nline" || results[1] != "second" {
+		t.Errorf("got %q", results)
+	}
+	if results, _ = ocr.ParseJPEG(fakeJPEG); len(results) != 5 {
+		t.Errorf("got %q, want results untouched without the option", results)
+	}
+}
+
+func TestSetMinBoxArea(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{
+			{Text: "text", Rect: image.Rect(0, 0, 40, 20)},
+			{Text: ".", Rect: image.Rect(50, 15, 53, 18)},
+			{Text: "more", Rect: image.Rect(60, 0, 80, 20)},
+		}
+	})
+	results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetMinBoxArea(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[text more]" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestSetMinResults(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"3", "5", " ", "0"}
+	})
+	_, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetMinResults(4), baiduocr.CleanResults())
+	if !errors.Is(err, baiduocr.ErrTooFewResults) {
+		t.Fatalf("got %v, want ErrTooFewResults", err)
+	}
+	if !strings.Contains(err.Error(), "got 3, want at least 4") {
+		t.Errorf("error %q does not have the counts", err)
+	}
+	if results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetMinResults(4)); err != nil || len(results) != 4 {
+		t.Errorf("got %q, %v", results, err)
+	}
+}
+
+func TestStableOrder(t *testing.T) {
+	responses := [][]baiduocr.Word{
+		{{Text: "b", Rect: image.Rect(0, 0, 10, 10)}, {Text: "a", Rect: image.Rect(0, 0, 10, 10)}, {Text: "c", Rect: image.Rect(20, 0, 30, 10)}, {Text: "z"}, {Text: "y"}},
+		{{Text: "y"}, {Text: "c", Rect: image.Rect(20, 0, 30, 10)}, {Text: "z"}, {Text: "a", Rect: image.Rect(0, 0, 10, 10)}, {Text: "b", Rect: image.Rect(0, 0, 10, 10)}},
+	}
+	call := 0
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		call++
+		return responses[call-1]
+	})
+	for range responses {
+		results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.StableOrder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(results); got != "[a b c y z]" {
+			t.Errorf("got %s", got)
+		}
+	}
+}
+
+func TestAipStableOrderKeepsBaiduLines(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		ret := wordsResult("c", "", "b", "a").(map[string]interface{})
+		ret["paragraphs_result"] = []map[string][]int{{"words_result_idx": {0, 1, 3}}, {"words_result_idx": {2}}}
+		return ret
+	})
+	// lines still group the same words, in the order of Baidu
+	lines, err := aip.ParseLines("general_basic", fakeJPEG, baiduocr.GroupByBaiduLines(), baiduocr.StableOrder(), baiduocr.CleanResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(lines), "[[c a] [b]]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseDigits(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"3S6O", "l2 ３４", "x"}
+	})
+	results, err := ocr.ParseDigits(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[3 6 2 34]" {
+		t.Errorf("got %s", got)
+	}
+	if results, _ = ocr.ParseDigits(fakeJPEG, baiduocr.JoinDigits()); fmt.Sprint(results) != "[36234]" {
+		t.Errorf("got %v with JoinDigits", results)
+	}
+}
+
+func TestDetectScript(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"Hello", "中文", "ひらがなカタカナ", "한국어", "2024", "東京のタワー"}
+	})
+	words, _, err := ocr.ParseDetailed(fakeJPEG, baiduocr.DetectScript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scripts []string
+	for _, word := range words {
+		scripts = append(scripts, word.Script)
+	}
+	if got, want := fmt.Sprintf("%q", scripts), `["Latin" "Han" "Kana" "Hangul" "" "Kana"]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if words, _, _ = ocr.ParseDetailed(fakeJPEG); words[0].Script != "" {
+		t.Error("want no script without the option")
+	}
+}
+
+func TestSingleLineMode(t *testing.T) {
+	var detectType string
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		detectType = r.FormValue("detecttype")
+		return []baiduocr.Word{
+			{Text: "7c", Rect: image.Rect(30, 2, 50, 20)},
+			{Text: "x4", Rect: image.Rect(0, 0, 25, 18)},
+		}
+	})
+	words, _, err := ocr.ParseDetailed(fakeJPEG, baiduocr.SingleLineMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detectType != "Recognize" {
+		t.Errorf("got detecttype %q, want Recognize", detectType)
+	}
+	if len(words) != 1 || words[0].Text != "x47c" || words[0].Rect != image.Rect(0, 0, 50, 20) {
+		t.Errorf("got %+v", words)
+	}
+	// characters of the line are counted
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SingleLineMode(), baiduocr.SetMinResults(4)); err != nil {
+		t.Error(err)
+	}
+	if _, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SingleLineMode(), baiduocr.SetMinResults(5)); !errors.Is(err, baiduocr.ErrTooFewResults) {
+		t.Errorf("got %v, want ErrTooFewResults", err)
+	}
+	if ocr.ParseJPEG(fakeJPEG); detectType != "LocateRecognize" {
+		t.Errorf("got detecttype %q by default", detectType)
+	}
+}
+
+func TestSetCorrections(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"BAlDU", "0CR", "noise", "ok"}
+	})
+	results, err := ocr.ParseJPEG(fakeJPEG,
+		baiduocr.SetCorrections(map[string]string{"BAlDU": "BAIDU", "noise": ""}),
+		baiduocr.SetCorrectionFunc(func(text string) string { return strings.Replace(text, "0", "O", -1) }),
+		baiduocr.CleanResults(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[BAIDU OCR ok]" {
+		t.Errorf("got %s", got)
+	}
+	// corrected before joining
+	results, err = ocr.ParseJPEG(fakeJPEG, baiduocr.SetCorrections(map[string]string{"noise": "-"}), baiduocr.SingleLineMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(results); got != "[BAlDU0CR-ok]" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestAutoCleanNoise(t *testing.T) {
+	var specks int
+	ocr := newWordsTestServer(t, func(r *http.Request) (words []baiduocr.Word) {
+		for i := 0; i < 8; i++ {
+			words = append(words, baiduocr.Word{Text: fmt.Sprint(i), Rect: image.Rect(i*30, 0, i*30+20, 20)})
+		}
+		for i := 0; i < specks; i++ {
+			words = append(words, baiduocr.Word{Text: ".", Rect: image.Rect(i*30, 40, i*30+3, 43)})
+		}
+		return append(words, baiduocr.Word{Text: "unlocated"})
+	})
+	for _, test := range []struct {
+		specks int
+		want   string
+	}{
+		{1, "01234567.unlocated"},
+		{3, "01234567unlocated"},
+	} {
+		specks = test.specks
+		results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.AutoCleanNoise())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(results, ""); got != test.want {
+			t.Errorf("with %d specks got %s, want %s", test.specks, got, test.want)
+		}
+	}
+
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		var ret []map[string]interface{}
+		for i, confidence := range []float64{0.9, 0.95, 0.2, 0.92, 0.1, 0.88, 0.97, 0.91} {
+			ret = append(ret, map[string]interface{}{
+				"words":       fmt.Sprint(i),
+				"location":    map[string]int{"left": i * 30, "top": 0, "width": 20, "height": 20},
+				"probability": map[string]float64{"average": confidence},
+			})
+		}
+		return map[string]interface{}{"words_result": ret, "words_result_num": len(ret)}
+	})
+	results, err := aip.GeneralBasic(fakeJPEG, baiduocr.AutoCleanNoise())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(results, ""); got != "013567" {
+		t.Errorf("got %s, want low confidence words dropped", got)
+	}
+}
+
+func TestSetTrimMode(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{" a1 ", "\u3000b2\t", "  "}
+	})
+	for _, test := range []struct {
+		mode baiduocr.TrimMode
+		want string
+	}{
+		{baiduocr.TrimNone, "[\" a1 \" \"\\u3000b2\\t\" \"  \"]"},
+		{baiduocr.TrimBoth, "[\"a1\" \"b2\" \"\"]"},
+		{baiduocr.TrimLeading, "[\"a1 \" \"b2\\t\" \"\"]"},
+		{baiduocr.TrimTrailing, "[\" a1\" \"\\u3000b2\" \"\"]"},
+	} {
+		results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetTrimMode(test.mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%q", results); got != test.want {
+			t.Errorf("mode %d: got %s, want %s", test.mode, got, test.want)
+		}
+	}
+	if results, _ := ocr.ParseJPEG(fakeJPEG, baiduocr.SetTrimMode(baiduocr.TrimBoth), baiduocr.CleanResults()); len(results) != 2 {
+		t.Errorf("got %q, want whitespace result dropped", results)
+	}
+}
+
+func TestSetMaxTotalChars(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"ab", "中文字", "cd"}
+	})
+	for _, test := range []struct {
+		max       int
+		want      string
+		truncated bool
+	}{
+		{0, "[\"ab\" \"中文字\" \"cd\"]", false},
+		{7, "[\"ab\" \"中文字\" \"cd\"]", false},
+		{4, "[\"ab\" \"中文\"]", true},
+		{2, "[\"ab\"]", true},
+	} {
+		words, meta, err := ocr.ParseDetailed(fakeJPEG, baiduocr.SetMaxTotalChars(test.max))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []string
+		for _, word := range words {
+			texts = append(texts, word.Text)
+		}
+		if got := fmt.Sprintf("%q", texts); got != test.want || meta.Truncated != test.truncated {
+			t.Errorf("max %d: got %s truncated %t, want %s truncated %t", test.max, got, meta.Truncated, test.want, test.truncated)
+		}
+	}
+	words, meta, err := ocr.ParseDetailed(fakeJPEG, baiduocr.SetMaxTotalChars(3), baiduocr.SingleLineMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 1 || words[0].Text != "ab中" || !meta.Truncated {
+		t.Errorf("single line: got %+v truncated %t, want \"ab中\" truncated", words, meta.Truncated)
+	}
+}
+
+func TestSortByConfidence(t *testing.T) {
+	aip, _ := newAipTestServer(t, func(endpoint, token string, r *http.Request) interface{} {
+		ret := []map[string]interface{}{{"words": "none"}}
+		for i, confidence := range []float64{0.5, 0.9, 0.7, 0.9} {
+			ret = append(ret, map[string]interface{}{
+				"words":       fmt.Sprint(i),
+				"probability": map[string]float64{"average": confidence},
+			})
+		}
+		return map[string]interface{}{"words_result": ret, "words_result_num": len(ret)}
+	})
+	for _, test := range []struct {
+		options []baiduocr.BaiduOCROption
+		want    string
+	}{
+		{nil, "none,0,1,2,3"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SortByConfidence()}, "1,3,2,0,none"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SortByConfidence(), baiduocr.SetMaxResults(1)}, "1"},
+		{[]baiduocr.BaiduOCROption{baiduocr.SetMaxResults(2)}, "none,0"},
+	} {
+		results, err := aip.GeneralBasic(fakeJPEG, test.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(results, ","); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{"c", "a", "b"}
+	})
+	results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SortByConfidence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(results, ","); got != "c,a,b" {
+		t.Errorf("got %s, want the order kept without confidence", got)
+	}
+}
+
+func TestSetCaseMode(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		return []string{" aB1 ", "中文", "Ωé"}
+	})
+	for _, test := range []struct {
+		mode baiduocr.CaseMode
+		want string
+	}{
+		{baiduocr.CaseUnchanged, "[\"aB1\" \"中文\" \"Ωé\"]"},
+		{baiduocr.CaseUpper, "[\"AB1\" \"中文\" \"ΩÉ\"]"},
+		{baiduocr.CaseLower, "[\"ab1\" \"中文\" \"ωé\"]"},
+	} {
+		results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetCaseMode(test.mode), baiduocr.SetTrimMode(baiduocr.TrimBoth))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%q", results); got != test.want {
+			t.Errorf("mode %d: got %s, want %s", test.mode, got, test.want)
+		}
+	}
+	// corrections see the text as recognized
+	results, err := ocr.ParseJPEG(fakeJPEG, baiduocr.SetCaseMode(baiduocr.CaseUpper), baiduocr.SetCorrections(map[string]string{" aB1 ": "ok"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0] != "OK" {
+		t.Errorf("got %q, want the case changed after corrections", results[0])
+	}
+}
+
+func TestRawWordCount(t *testing.T) {
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		return []baiduocr.Word{
+			{Text: "a", Rect: image.Rect(0, 0, 20, 20)},
+			{Text: ".", Rect: image.Rect(30, 0, 32, 2)},
+			{Text: ",", Rect: image.Rect(40, 0, 42, 2)},
+		}
+	})
+	for _, options := range [][]baiduocr.BaiduOCROption{
+		{baiduocr.SetMinBoxArea(10)},
+		{baiduocr.SingleLineMode()},
+	} {
+		words, meta, err := ocr.ParseDetailed(fakeJPEG, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(words) != 1 || meta.RawWordCount != 3 {
+			t.Errorf("got %d words of %d, want 1 of 3", len(words), meta.RawWordCount)
+		}
+	}
+}
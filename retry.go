@@ -0,0 +1,114 @@
+package baiduocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const _DEFAULT_RETRY_BACKOFF = 500 * time.Millisecond
+
+// serverError is returned when Baidu OCR answers with a 5xx status.
+type serverError struct {
+	status string
+}
+
+func (err serverError) Error() string {
+	return "BaiduOCR server error: " + err.status
+}
+
+// RetryBudget caps the number of retries of all the calls sharing it, so
+// that many failing calls do not retry all at once and make an outage of
+// Baidu OCR worse. It is a token bucket: every retry takes a token, and when
+// there is none left, calls fail with their last error right away instead of
+// retrying. Tokens are added back at a steady rate, up to the size of the
+// bucket. Create one with NewRetryBudget and set it in OCR.RetryBudget of all
+// the clients to limit together. It is safe for concurrent use.
+type RetryBudget struct {
+	mutex     sync.Mutex
+	tokens    float64
+	max       float64
+	perSecond float64
+	updatedAt time.Time
+}
+
+// ErrRetryBudgetExhausted is wrapped with the last error of a call which
+// could not retry because its RetryBudget is exhausted.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// Create a full retry budget allowing bursts of max retries, refilled with
+// perSecond retries every second.
+func NewRetryBudget(max int, perSecond float64) *RetryBudget {
+	return &RetryBudget{tokens: float64(max), max: float64(max), perSecond: perSecond, updatedAt: currentClock.now()}
+}
+
+// take takes a token from the budget, reporting false if there is none.
+func (budget *RetryBudget) take() bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	now := currentClock.now()
+	budget.tokens += now.Sub(budget.updatedAt).Seconds() * budget.perSecond
+	if budget.tokens > budget.max {
+		budget.tokens = budget.max
+	}
+	budget.updatedAt = now
+	if budget.tokens < 1 {
+		return false
+	}
+	budget.tokens--
+	return true
+}
+
+// requestWithRetries sends the request, retrying after network and server
+// errors and maintenance pages as configured, within MaxTotalDuration and RetryBudget.
+func (ocr OCR) requestWithRetries(imageBytes []byte, opts baiduOCROption) (words []Word, meta ResultMeta, err error) {
+	parent := opts.context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx := parent
+	if ocr.MaxTotalDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, ocr.MaxTotalDuration)
+		defer cancel()
+		opts.context = ctx
+	}
+
+	backoff := ocr.RetryBackoff
+	if backoff <= 0 {
+		backoff = _DEFAULT_RETRY_BACKOFF
+	}
+	for attempt := 0; ; attempt++ {
+		words, meta, err = ocr.request(imageBytes, opts)
+		if err == nil || attempt >= ocr.MaxRetries || !isRetryable(err) || ctx.Err() != nil {
+			break
+		}
+		if ocr.RetryBudget != nil && !ocr.RetryBudget.take() {
+			err = fmt.Errorf("%w: %v", ErrRetryBudgetExhausted, err)
+			break
+		}
+		if currentClock.sleep(ctx, backoff) != nil {
+			break
+		}
+		backoff *= 2
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		err = fmt.Errorf("total deadline exceeded: %w", err)
+	}
+	return
+}
+
+// isRetryable reports whether the request may succeed if sent again: it
+// timed out, the connection failed, or the server failed. Other errors of
+// the transport, such as an unsupported scheme or an invalid certificate,
+// are permanent, although every *url.Error is a net.Error.
+func isRetryable(err error) bool {
+	var netErr net.Error
+	var opErr *net.OpError
+	var srvErr serverError
+	return errors.As(err, &netErr) && netErr.Timeout() || errors.As(err, &opErr) ||
+		errors.As(err, &srvErr) || errors.Is(err, ErrServiceUnavailable)
+}
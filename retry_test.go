@@ -0,0 +1,145 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"retData":[{"word":"ok"}]}`))
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIPath: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond}
+	results, err := ocr.ParseJPEG(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || calls != 3 {
+		t.Errorf("got %v after %d calls", results, calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	ocr.MaxRetries = 1
+	if _, err = ocr.ParseJPEG(fakeJPEG); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("got error %v, want server error", err)
+	}
+}
+
+func TestMaxTotalDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusBadGateway)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIPath: server.URL, MaxRetries: 100, RetryBackoff: 20 * time.Millisecond, MaxTotalDuration: 100 * time.Millisecond}
+	start := time.Now()
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v, want about 100ms", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "total deadline exceeded") || !strings.Contains(err.Error(), "502") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIPath: server.URL, MaxRetries: 5, RetryBackoff: time.Millisecond, RetryBudget: baiduocr.NewRetryBudget(3, 0)}
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = ocr.ParseJPEG(fakeJPEG)
+		}(i)
+	}
+	wg.Wait()
+	// 4 first attempts and the 3 retries of the budget
+	if calls != 7 {
+		t.Errorf("got %d calls, want 7", calls)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, baiduocr.ErrRetryBudgetExhausted) || !strings.Contains(err.Error(), "503") {
+			t.Errorf("got error %v", err)
+		}
+	}
+}
+
+func TestPermanentTransportErrorNotRetried(t *testing.T) {
+	var attempts int32
+	ocr := baiduocr.OCR{
+		APIPath:      "ftp://example.com/ocr",
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+		AuditFunc:    func(baiduocr.AuditRecord) { atomic.AddInt32(&attempts, 1) },
+	}
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	if err == nil || !strings.Contains(err.Error(), "unsupported protocol scheme") {
+		t.Fatalf("got %v, want unsupported protocol scheme", err)
+	}
+	if attempts != 1 {
+		t.Errorf("sent %d times, want no retries", attempts)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	atomic.StoreInt32(&attempts, 0)
+	ocr.APIPath = closed.URL
+	if _, err := ocr.ParseJPEG(fakeJPEG); err == nil {
+		t.Fatal("want connection refused")
+	}
+	if attempts != 4 {
+		t.Errorf("sent %d times, want a refused connection retried 3 times", attempts)
+	}
+}
+
+func TestRetryBackoffClock(t *testing.T) {
+	clock := baiduocr.UseFakeClock(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIPath: server.URL, MaxRetries: 3, RetryBackoff: time.Second}
+	_, err := ocr.ParseJPEG(fakeJPEG)
+	if err == nil {
+		t.Fatal("want error")
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if got := clock.Sleeps(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("slept %v, want %v", got, want)
+	}
+
+	// a budget of one retry refills in two seconds of the clock, one of which
+	// is slept by the backoff
+	ocr.MaxRetries, ocr.RetryBudget = 1, baiduocr.NewRetryBudget(1, 0.5)
+	if _, err = ocr.ParseJPEG(fakeJPEG); errors.Is(err, baiduocr.ErrRetryBudgetExhausted) {
+		t.Errorf("got error %v, want a retry", err)
+	}
+	if _, err = ocr.ParseJPEG(fakeJPEG); !errors.Is(err, baiduocr.ErrRetryBudgetExhausted) {
+		t.Errorf("got error %v, want budget exhausted", err)
+	}
+	clock.Advance(time.Second)
+	if _, err = ocr.ParseJPEG(fakeJPEG); errors.Is(err, baiduocr.ErrRetryBudgetExhausted) {
+		t.Errorf("got error %v, want a retry after refill", err)
+	}
+}
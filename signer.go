@@ -0,0 +1,130 @@
+package baiduocr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _BCE_EXPIRATION_PERIOD = 1800
+
+type (
+	// Signer signs requests for endpoints authenticating requests by their
+	// signature instead of an API key or an access token. Sign is called with
+	// each request, including retries, just before it is sent, and may set
+	// its headers or URL parameters. It must not read the body.
+	Signer interface {
+		Sign(req *http.Request) error
+	}
+
+	// BCESigner signs requests with the bce-auth-v1 authorization of the
+	// signed Baidu AI Cloud (BCE) APIs, computed from an access key and a
+	// secret key. It signs the method, path and query of the request and its
+	// host, content-length, content-type, content-md5 and x-bce-* headers,
+	// setting x-bce-date and authorization.
+	BCESigner struct {
+		// Set access key ID of your Baidu AI Cloud account
+		AccessKeyID string
+		// Set secret access key of your Baidu AI Cloud account
+		SecretAccessKey string
+		// Set number of seconds the signature is valid for, default is 1800
+		ExpirationPeriodInSeconds int
+		// Set function returning the time of signing, default is time.Now
+		Now func() time.Time
+	}
+)
+
+// Sign sets the authorization header of the request.
+func (signer BCESigner) Sign(req *http.Request) error {
+	if signer.AccessKeyID == "" || signer.SecretAccessKey == "" {
+		return errors.New("BCESigner needs both an access key ID and a secret access key")
+	}
+	now := time.Now
+	if signer.Now != nil {
+		now = signer.Now
+	}
+	expiration := signer.ExpirationPeriodInSeconds
+	if expiration <= 0 {
+		expiration = _BCE_EXPIRATION_PERIOD
+	}
+	timestamp := now().UTC().Format("2006-01-02T15:04:05Z")
+	req.Header.Set("x-bce-date", timestamp)
+
+	headers := map[string]string{"host": req.Host}
+	if headers["host"] == "" {
+		headers["host"] = req.URL.Host
+	}
+	if req.ContentLength > 0 {
+		headers["content-length"] = strconv.FormatInt(req.ContentLength, 10)
+	}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "content-md5" || strings.HasPrefix(name, "x-bce-") {
+			headers[name] = strings.TrimSpace(values[0])
+		}
+	}
+	var signedHeaders, canonicalHeaders []string
+	for name := range headers {
+		signedHeaders = append(signedHeaders, name)
+	}
+	sort.Strings(signedHeaders)
+	for _, name := range signedHeaders {
+		canonicalHeaders = append(canonicalHeaders, bceEscape(name)+":"+bceEscape(headers[name]))
+	}
+
+	var canonicalQuery []string
+	for name, values := range req.URL.Query() {
+		if strings.ToLower(name) == "authorization" {
+			continue
+		}
+		for _, value := range values {
+			canonicalQuery = append(canonicalQuery, bceEscape(name)+"="+bceEscape(value))
+		}
+	}
+	sort.Strings(canonicalQuery)
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		strings.Replace(bceEscape(path), "%2F", "/", -1),
+		strings.Join(canonicalQuery, "&"),
+		strings.Join(canonicalHeaders, "\n"),
+	}, "\n")
+
+	prefix := fmt.Sprintf("bce-auth-v1/%s/%s/%d", signer.AccessKeyID, timestamp, expiration)
+	signingKey := hmacHex(signer.SecretAccessKey, prefix)
+	signature := hmacHex(signingKey, canonicalRequest)
+	req.Header.Set("authorization", prefix+"/"+strings.Join(signedHeaders, ";")+"/"+signature)
+	return nil
+}
+
+// bceEscape percent-encodes every byte of s except the unreserved characters
+// A-Z, a-z, 0-9, "-", ".", "_" and "~", as BCE canonical requests do.
+func bceEscape(s string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+func hmacHex(key, message string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
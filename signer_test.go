@@ -0,0 +1,83 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestBCESigner(t *testing.T) {
+	// the example of the BCE authentication documentation
+	req, err := http.NewRequest("PUT", "http://bj.bcebos.com/v1/test/myfolder/readme.txt?partNumber=9&uploadId=a44cc9bab11cbd156984767aad637851", strings.NewReader("Example\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Content-Md5", "NFzcPqhviddjRNnSOGo4rw==")
+	signer := baiduocr.BCESigner{
+		AccessKeyID:     "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		SecretAccessKey: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		Now:             func() time.Time { return time.Date(2015, 4, 27, 16, 23, 49, 0, time.FixedZone("CST", 8*3600)) },
+	}
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := req.Header.Get("x-bce-date"), "2015-04-27T08:23:49Z"; got != want {
+		t.Errorf("got x-bce-date %s, want %s", got, want)
+	}
+	want := "bce-auth-v1/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa/2015-04-27T08:23:49Z/1800/content-length;content-md5;content-type;host;x-bce-date/d74a04362e6a848f5b39b15421cb449427f419c95a480fd6b8cf9fc783e2999e"
+	if got := req.Header.Get("authorization"); got != want {
+		t.Errorf("got authorization\n%s, want\n%s", got, want)
+	}
+
+	if err := (baiduocr.BCESigner{AccessKeyID: "ak"}).Sign(req); err == nil {
+		t.Error("got no error without secret access key")
+	}
+}
+
+func TestAipSigner(t *testing.T) {
+	var authorization, token string
+	aip, tokens := newAipTestServer(t, func(endpoint, accessToken string, r *http.Request) interface{} {
+		authorization, token = r.Header.Get("authorization"), accessToken
+		return wordsResult("signed")
+	})
+	aip.Signer = baiduocr.BCESigner{AccessKeyID: "ak", SecretAccessKey: "sk"}
+	results, err := aip.GeneralBasic(fakeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(results) != "[signed]" || !strings.HasPrefix(authorization, "bce-auth-v1/ak/") {
+		t.Errorf("got %v with authorization %q", results, authorization)
+	}
+	if *tokens != 0 || token != "" {
+		t.Errorf("got %d token requests and access token %q, want none", *tokens, token)
+	}
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Sign(*http.Request) error { return errors.New("no credentials") }
+
+func TestSignerErrorClosesBody(t *testing.T) {
+	ocr := baiduocr.OCR{APIPath: "http://127.0.0.1:1/", Signer: failingSigner{}}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		if _, err := ocr.ParseJPEG(fakeJPEG); err == nil || !strings.Contains(err.Error(), "no credentials") {
+			t.Fatalf("got %v, want the error of the signer", err)
+		}
+	}
+	// the goroutines writing the bodies exit once they are closed
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before+5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before+5 {
+		t.Errorf("%d goroutines after 50 failed calls, %d before", n, before)
+	}
+}
@@ -0,0 +1,84 @@
+package baiduocr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// images of at most this many colors are graphics
+	_SMART_FORMAT_MAX_COLORS = 256
+	// or of at least this share of pixels equal to their left neighbour
+	_SMART_FORMAT_MIN_FLAT = 0.75
+)
+
+// Option to let AipOCR submit graphics, such as screenshots and rendered
+// text, as PNG, and photos as JPEG, instead of always JPEG. PNG keeps the
+// sharp edges of graphics that JPEG blurs and, for images of few colors, is
+// often smaller too, while JPEG is much smaller for photos. An image is a
+// graphic if it has at most 256 colors, or if at least 3/4 of its pixels are
+// of the exact color of the pixel on their left, as the noise of cameras and
+// of JPEG compression leaves almost none such in photos. It is applied after
+// preprocessing, and transparency is removed as for JPEG, over black unless
+// SetPNGBackgroundColor is used. JPEG images without preprocessing options
+// are submitted unchanged, as encoding them to PNG keeps their artifacts.
+// The AIP endpoints accept both formats; OCR always submits JPEG, the only
+// format its apistore endpoint is known to accept.
+func SmartFormat() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.smartFormat = true }}
+}
+
+// toSubmitted returns the image to submit to the AIP endpoints, in the
+// format chosen by SmartFormat, or JPEG without it.
+func toSubmitted(imageBytes []byte, opts baiduOCROption) (output []byte, err error) {
+	if !opts.smartFormat || http.DetectContentType(imageBytes) == "image/jpeg" && len(opts.transforms) == 0 {
+		return toJPEG(imageBytes, opts)
+	}
+	var img image.Image
+	img, err = normalizedImage(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	done := opts.track(encodePhase)
+	defer done()
+	if !isGraphic(img) {
+		return encodeJPEGBytes(img, opts)
+	}
+	// as in JPEG, transparent pixels left by the background color being
+	// unset become black, rather than whatever Baidu draws them over
+	if opts.pngBackgroundColor == nil {
+		img = flattenPNG(img, color.Black)
+	}
+	var buffer bytes.Buffer
+	err = png.Encode(&buffer, img)
+	output = buffer.Bytes()
+	return
+}
+
+// isGraphic tells if img is a graphic rather than a photo, see SmartFormat.
+func isGraphic(img image.Image) bool {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return false
+	}
+	colors := map[color.RGBA]bool{}
+	flat := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		var left color.RGBA
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			if x > bounds.Min.X && c == left {
+				flat++
+			}
+			left = c
+			if len(colors) <= _SMART_FORMAT_MAX_COLORS {
+				colors[c] = true
+			}
+		}
+	}
+	return len(colors) <= _SMART_FORMAT_MAX_COLORS ||
+		float64(flat) >= _SMART_FORMAT_MIN_FLAT*float64(bounds.Dx()*bounds.Dy())
+}
@@ -0,0 +1,182 @@
+package baiduocr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StreamResult is a word recognized by ParseStream, or the error ending the
+// stream.
+type StreamResult struct {
+	Word Word
+	Err  error
+}
+
+// Read text from JPEG/PNG image, sending each recognized word on the returned
+// channel as soon as it is received, which is closed after the last word or
+// after an error. The retData array of the apistore endpoint is decoded
+// entry by entry while the response body is still arriving, so that callers
+// can start working on the first words of a large document before the rest
+// is downloaded, if the endpoint sends the body in pieces. Only the retData
+// of the apistore shape streams: a words_result response, such as from a
+// gateway to an AIP endpoint, is read in full before its words are sent, as
+// are responses of endpoints sending the body at once. Results are not
+// cached or retried, and the result options needing all the words,
+// StableOrder, SetMinResults, AutoCleanNoise and the joining of
+// SingleLineMode, are ignored. Read the channel to the end, or cancel the
+// context set by SetContext to stop early.
+func (ocr OCR) ParseStream(imageBytes []byte, options ...BaiduOCROption) <-chan StreamResult {
+	results := make(chan StreamResult)
+	go func() {
+		defer close(results)
+		opts, timedOut, cancel := ocr.newOptions(options).withOperationTimeout(ocr.OperationTimeout)
+		defer cancel()
+		ctx := opts.context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		emit := func(result StreamResult) bool {
+			select {
+			case results <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if err := timedOut(ocr.stream(imageBytes, opts, emit)); err != nil {
+			emit(StreamResult{Err: err})
+		}
+	}()
+	return results
+}
+
+func (ocr OCR) stream(imageBytes []byte, opts baiduOCROption, emit func(StreamResult) bool) (err error) {
+	err = opts.checkImageRequest()
+	if err != nil {
+		return
+	}
+	imageBytes, err = toJPEG(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	err = checkBlank(imageBytes, opts)
+	if err != nil {
+		return
+	}
+	var release func()
+	release, err = ocr.acquireSlot(opts.context)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	var req *http.Request
+	var requestID string
+	req, requestID, err = ocr.newRequest(imageBytes, opts)
+	if requestID != "" {
+		defer func() {
+			if err != nil {
+				err = fmt.Errorf("%w (request ID %s)", err, requestID)
+			}
+		}()
+	}
+	if err != nil {
+		return
+	}
+	var body io.ReadCloser
+	body, _, err = sendRequest(ocr.HTTPClient, ocr.TimeoutInMilliseconds, ocr.TimeoutMode, ocr.Signer, req)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	size := imageSize(imageBytes)
+	sent := 0
+	send := func(word Word) bool {
+		if word, ok := opts.keep(word); ok {
+			word.NormalizedRect = normalizeRect(word.Rect, size)
+			sent++
+			return emit(StreamResult{Word: word})
+		}
+		return true
+	}
+	err = decodeStream(opts.newUTF8Reader(body), send)
+	if err == nil && sent == 0 {
+		err = opts.formatted(noTextError(""))
+	}
+	return
+}
+
+// decodeStream decodes a response, passing the words of its retData array to
+// send one by one. Other fields are buffered, and if the response has no
+// retData, it is parsed in full when read to the end. It stops if send
+// returns false.
+func decodeStream(reader io.Reader, send func(Word) bool) error {
+	decoder := json.NewDecoder(reader)
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return errors.New("response is not a JSON object")
+	}
+	streamed := false
+	fields := map[string]json.RawMessage{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		if key != "retData" {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return err
+			}
+			fields[key] = value
+			continue
+		}
+		if token, err := decoder.Token(); err != nil {
+			return err
+		} else if token != json.Delim('[') {
+			// not an array, such as null
+			continue
+		}
+		for decoder.More() {
+			var data baiduOCRWord
+			if err := decoder.Decode(&data); err != nil {
+				return err
+			}
+			streamed = true
+			if !send(data.word()) {
+				return nil
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+	if streamed {
+		return nil
+	}
+	whole, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var meta ResultMeta
+	words, err := parseResponse(whole, &meta)
+	if errors.Is(err, ErrNoText) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		if !send(word) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package baiduocr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseStream(t *testing.T) {
+	received := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errNum":0,"retData":[{"rect":{"left":"1","top":"2","width":"3","height":"4"},"word":"first"}`)
+		w.(http.Flusher).Flush()
+		// the rest is only sent once the first word is out
+		<-received
+		fmt.Fprint(w, `,{"word":"second"}]}`)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+
+	var words []string
+	for result := range ocr.ParseStream(fakeJPEG) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if len(words) == 0 {
+			close(received)
+		}
+		words = append(words, result.Word.Text)
+	}
+	if fmt.Sprint(words) != "[first second]" {
+		t.Errorf("got %v", words)
+	}
+}
+
+func TestParseStreamBuffered(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL}
+	collect := func() (words []string, err error) {
+		for result := range ocr.ParseStream(fakeJPEG) {
+			if result.Err != nil {
+				err = result.Err
+				continue
+			}
+			words = append(words, result.Word.Text)
+		}
+		return
+	}
+
+	response = `{"words_result":[{"words":"gateway"}],"words_result_num":1}`
+	if words, err := collect(); err != nil || fmt.Sprint(words) != "[gateway]" {
+		t.Errorf("got %v, %v", words, err)
+	}
+
+	response = `{"errNum":300202,"errMsg":"Missing apikey","retData":[]}`
+	if _, err := collect(); err == nil || !strings.Contains(err.Error(), "300202") {
+		t.Errorf("got %v, want error of the endpoint", err)
+	}
+
+	response = `{"errNum":0,"retData":[]}`
+	if words, err := collect(); len(words) != 0 || !errors.Is(err, baiduocr.ErrNoText) {
+		t.Errorf("got %v, %v, want no text error", words, err)
+	}
+}
+
+func TestParseStreamChecksOptions(t *testing.T) {
+	var calls int32
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		atomic.AddInt32(&calls, 1)
+		return []string{"ok"}
+	})
+	for _, option := range []baiduocr.BaiduOCROption{baiduocr.SetVersion(""), baiduocr.SetImageType(baiduocr.ImageTypeURL)} {
+		var err error
+		for result := range ocr.ParseStream(fakeJPEG, option) {
+			err = result.Err
+		}
+		if err == nil {
+			t.Error("want an error for invalid options")
+		}
+	}
+	if calls != 0 {
+		t.Errorf("sent %d requests, want none", calls)
+	}
+}
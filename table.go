@@ -0,0 +1,157 @@
+package baiduocr
+
+import (
+	"math"
+	"sort"
+)
+
+// Read text from JPEG/PNG image of a table, such as a spreadsheet or a
+// receipt, and return it as rows of cells inferred from the positions of the
+// words:
+//
+// Rows are the lines of ParseLines, from top to bottom. In a row, words
+// separated by a horizontal gap smaller than the tolerance form one cell.
+//
+// Columns are clusters of cells overlapping horizontally, with each cell
+// widened by half the tolerance on both sides, so that right-aligned numbers
+// of different lengths still share a column. Cells are clustered from the
+// narrowest, and a cell overlapping more than one column is a merged cell: it
+// is put in the leftmost of them, and the others are left empty in its row.
+// Cells missing from a row are empty strings too, and cells falling in the
+// same column of a row are joined by a space.
+//
+// The tolerance is the width of a space, see SetLayoutSpaceWidth, which
+// defaults to the median width of a character of the words. Words without a
+// bounding box come last, each in a row by itself in the first column.
+func (ocr OCR) ParseTable(imageBytes []byte, options ...BaiduOCROption) (table [][]string, err error) {
+	var words []Word
+	words, _, err = ocr.ParseDetailed(imageBytes, options...)
+	if err != nil {
+		return
+	}
+	table = tableCells(words, ocr.newOptions(options))
+	return
+}
+
+// Same as OCR.ParseTable, with the named endpoint, which must locate text,
+// like general or accurate. With other endpoints each word is a row of one
+// cell.
+func (aip *AipOCR) ParseTable(endpoint string, imageBytes []byte, options ...BaiduOCROption) (table [][]string, err error) {
+	var words []Word
+	words, _, err = aip.ParseDetailed(endpoint, imageBytes, options...)
+	if err != nil {
+		return
+	}
+	table = tableCells(words, newOptions(options))
+	return
+}
+
+type (
+	// tableCell is words next to each other in a row of a table.
+	tableCell struct {
+		row, column int
+		minX, maxX  int
+		text        string
+	}
+
+	// tableColumn is the horizontal extent of a column of a table.
+	tableColumn struct {
+		minX, maxX float64
+	}
+)
+
+// tableCells lays out the words in rows and columns by their positions, as
+// described in OCR.ParseTable.
+func tableCells(words []Word, opts baiduOCROption) (table [][]string) {
+	tolerance := opts.layoutSpaceWidth
+	if tolerance <= 0 {
+		tolerance = medianCharWidth(words)
+	}
+	var cells []*tableCell
+	rows := groupLines(words)
+	for i, row := range rows {
+		var cell *tableCell
+		for _, word := range row {
+			if word.Rect.Empty() {
+				cells = append(cells, &tableCell{row: i, text: word.Text})
+				continue
+			}
+			if cell != nil && float64(word.Rect.Min.X-cell.maxX) < tolerance {
+				cell.text += " " + word.Text
+				if word.Rect.Max.X > cell.maxX {
+					cell.maxX = word.Rect.Max.X
+				}
+				continue
+			}
+			cell = &tableCell{row: i, minX: word.Rect.Min.X, maxX: word.Rect.Max.X, text: word.Text}
+			cells = append(cells, cell)
+		}
+	}
+	if len(cells) == 0 {
+		return
+	}
+
+	var located []*tableCell
+	for _, cell := range cells {
+		if cell.maxX > cell.minX {
+			located = append(located, cell)
+		}
+	}
+	sort.SliceStable(located, func(i, j int) bool {
+		return located[i].maxX-located[i].minX < located[j].maxX-located[j].minX
+	})
+	var columns []*tableColumn
+	owners := map[*tableCell]*tableColumn{}
+	for _, cell := range located {
+		minX, maxX := float64(cell.minX)-tolerance/2, float64(cell.maxX)+tolerance/2
+		var overlapped []*tableColumn
+		for _, column := range columns {
+			if minX < column.maxX && column.minX < maxX {
+				overlapped = append(overlapped, column)
+			}
+		}
+		switch len(overlapped) {
+		case 0:
+			column := &tableColumn{minX: minX, maxX: maxX}
+			columns = append(columns, column)
+			owners[cell] = column
+		case 1:
+			column := overlapped[0]
+			column.minX, column.maxX = math.Min(column.minX, minX), math.Max(column.maxX, maxX)
+			owners[cell] = column
+		default:
+			leftmost := overlapped[0]
+			for _, column := range overlapped[1:] {
+				if column.minX < leftmost.minX {
+					leftmost = column
+				}
+			}
+			owners[cell] = leftmost
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].minX < columns[j].minX })
+	indexes := map[*tableColumn]int{}
+	for i, column := range columns {
+		indexes[column] = i
+	}
+
+	width := len(columns)
+	if width == 0 {
+		width = 1
+	}
+	table = make([][]string, len(rows))
+	for i := range table {
+		table[i] = make([]string, width)
+	}
+	for _, cell := range cells {
+		if column, ok := owners[cell]; ok {
+			cell.column = indexes[column]
+		}
+		if text := &table[cell.row][cell.column]; *text != "" {
+			*text += " " + cell.text
+		} else {
+			*text = cell.text
+		}
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package baiduocr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Read text from several small images, such as captchas, with a single
+// request: the images are drawn in a grid of cols columns, each in a cell as
+// large as the largest image, gap pixels apart and from the edges, over the
+// PNG background color or white, and the words Baidu finds are mapped back to
+// the image whose cell contains the center of their box. The results of each
+// image are returned in the order of images, after the result options, such
+// as SingleLineMode, are applied to them on their own; images where no text
+// is found have empty results. It costs one request instead of len(images),
+// but Baidu reads small images in a large one less reliably than on their
+// own: a gap of about the height of the text keeps words of neighboring
+// images from being joined, yet Baidu may still miss text it would have found
+// in an image alone, so compare with ParseImage on samples before relying on
+// it. As the words must be located, the composite image is submitted with
+// detecttype=LocateRecognize even with SingleLineMode, and it fails if Baidu
+// returns words without boxes.
+func (ocr OCR) ParseTiled(images [][]byte, cols int, gap int, options ...BaiduOCROption) (results [][]string, err error) {
+	if cols < 1 || gap < 0 {
+		err = errors.New("cols must be at least 1 and gap at least 0")
+		return
+	}
+	if len(images) == 0 {
+		return
+	}
+	opts, timedOut, cancel := ocr.newOptions(options).withOperationTimeout(ocr.OperationTimeout)
+	defer cancel()
+	defer func() { err = timedOut(err) }()
+	err = opts.checkImageRequest()
+	if err != nil {
+		return
+	}
+	tiles := make([]image.Image, len(images))
+	var cell image.Point
+	for i, imageBytes := range images {
+		tiles[i], err = normalizedImage(imageBytes, opts)
+		if err != nil {
+			err = fmt.Errorf("image %d: %w", i, err)
+			return
+		}
+		size := tiles[i].Bounds().Size()
+		cell.X, cell.Y = max(cell.X, size.X), max(cell.Y, size.Y)
+	}
+	rows := (len(images) + cols - 1) / cols
+	pitch := cell.Add(image.Pt(gap, gap))
+	canvas := image.NewRGBA(image.Rect(0, 0, cols*pitch.X+gap, rows*pitch.Y+gap))
+	background := opts.pngBackgroundColor
+	if background == nil {
+		background = color.White
+	}
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+	offsets := make([]image.Point, len(tiles))
+	for i, tile := range tiles {
+		offsets[i] = image.Pt(gap+i%cols*pitch.X, gap+i/cols*pitch.Y)
+		bounds := tile.Bounds()
+		draw.Draw(canvas, bounds.Sub(bounds.Min).Add(offsets[i]), tile, bounds.Min, draw.Over)
+	}
+	err = opts.checkSize(canvas.Bounds().Size())
+	if err != nil {
+		return
+	}
+	var jpegBytes []byte
+	done := opts.track(encodePhase)
+	jpegBytes, err = encodeJPEGBytes(canvas, opts)
+	done()
+	if err != nil {
+		return
+	}
+
+	requestOpts := opts
+	requestOpts.singleLine = false
+	var words []Word
+	words, _, err = ocr.cachedRequest(jpegBytes, requestOpts)
+	if errors.Is(err, ErrNoText) {
+		return make([][]string, len(images)), nil
+	} else if err != nil {
+		return
+	}
+	tileWords := make([][]Word, len(images))
+	for _, word := range words {
+		if word.Rect.Empty() {
+			err = errors.New("Baidu returned words without boxes, which cannot be mapped to the images")
+			return
+		}
+		center := word.Rect.Min.Add(word.Rect.Max).Div(2)
+		col, row := center.X/pitch.X, center.Y/pitch.Y
+		i := row*cols + col
+		if col >= cols || i >= len(images) {
+			// in the empty cells after the last image
+			continue
+		}
+		word.Rect = word.Rect.Sub(offsets[i])
+		for j := range word.Quad {
+			word.Quad[j] = word.Quad[j].Sub(offsets[i])
+		}
+		word.Chars = append([]Char(nil), word.Chars...)
+		for j := range word.Chars {
+			word.Chars[j].Rect = word.Chars[j].Rect.Sub(offsets[i])
+		}
+		tileWords[i] = append(tileWords[i], word)
+	}
+	results = make([][]string, len(images))
+	for i := range tiles {
+		if len(tileWords[i]) == 0 {
+			continue
+		}
+		meta := ResultMeta{ImageSize: tiles[i].Bounds().Size()}
+		var kept []Word
+		kept, err = opts.postprocess(tileWords[i], &meta)
+		if errors.Is(err, ErrNoText) {
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("image %d: %w", i, err)
+			return
+		}
+		results[i] = wordTexts(kept)
+	}
+	return
+}
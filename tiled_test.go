@@ -0,0 +1,52 @@
+package baiduocr_test
+
+import (
+	"fmt"
+	"image"
+	"net/http"
+	"testing"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestParseTiled(t *testing.T) {
+	var size image.Point
+	ocr := newWordsTestServer(t, func(r *http.Request) []baiduocr.Word {
+		if r.FormValue("detecttype") != "LocateRecognize" {
+			t.Errorf("detecttype = %s, want LocateRecognize", r.FormValue("detecttype"))
+		}
+		if img := requestImage(t, r); img != nil {
+			size = img.Bounds().Size()
+		}
+		// cells of 20x10 pixels, 5 pixels apart, are at (5,5), (30,5) and (5,20)
+		return []baiduocr.Word{
+			{Text: "a2", Rect: image.Rect(15, 5, 25, 15)},
+			{Text: "b", Rect: image.Rect(30, 5, 50, 15)},
+			{Text: "a1", Rect: image.Rect(5, 5, 15, 15)},
+			{Text: "empty cell", Rect: image.Rect(30, 20, 50, 30)},
+		}
+	})
+	images := [][]byte{
+		encodePNG(t, image.NewGray(image.Rect(0, 0, 20, 10))),
+		encodePNG(t, image.NewGray(image.Rect(0, 0, 12, 8))),
+		encodePNG(t, image.NewGray(image.Rect(0, 0, 20, 10))),
+	}
+	results, err := ocr.ParseTiled(images, 2, 5, baiduocr.SingleLineMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%q", results); got != `[["a1a2"] ["b"] []]` {
+		t.Errorf("got %s", got)
+	}
+	if size != image.Pt(55, 35) {
+		t.Errorf("submitted %v, want 55x35", size)
+	}
+	if _, err := ocr.ParseTiled(images, 0, 5); err == nil {
+		t.Error("got no error for 0 columns")
+	}
+
+	unlocated := newTestServer(t, func(r *http.Request) []string { return []string{"ok"} })
+	if _, err := unlocated.ParseTiled(images, 2, 5); err == nil {
+		t.Error("got no error for words without boxes")
+	}
+}
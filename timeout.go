@@ -0,0 +1,124 @@
+package baiduocr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type (
+	// TimeoutMode sets what TimeoutInMilliseconds limits.
+	TimeoutMode int
+
+	// idleTimeout cancels a request when no data is sent or received for
+	// timeout.
+	idleTimeout struct {
+		timer    *time.Timer
+		timeout  time.Duration
+		once     sync.Once
+		timedOut chan struct{}
+	}
+
+	// idleReader restarts the idle timeout every time data is read, if
+	// there is one.
+	idleReader struct {
+		io.ReadCloser
+		idle *idleTimeout
+	}
+
+	// responseBody is a response body which calls done once closed.
+	responseBody struct {
+		io.ReadCloser
+		done func()
+	}
+)
+
+const (
+	// The timeout limits the whole request, from connecting to reading the
+	// end of the response. This is the default.
+	TimeoutTotal TimeoutMode = iota
+	// The timeout limits each wait for data: the request fails if nothing
+	// is sent or received for that long, however long the whole request
+	// takes. Use it for slow but steady connections, such as uploading large
+	// images over a mobile network.
+	TimeoutIdle
+)
+
+func newIdleTimeout(timeout time.Duration, cancel func()) *idleTimeout {
+	idle := &idleTimeout{timeout: timeout, timedOut: make(chan struct{})}
+	idle.timer = time.AfterFunc(timeout, func() {
+		idle.once.Do(func() { close(idle.timedOut) })
+		cancel()
+	})
+	return idle
+}
+
+func (idle *idleTimeout) stop() {
+	if idle != nil {
+		idle.timer.Stop()
+	}
+}
+
+// wrap returns err explaining the request was cancelled if it timed out.
+func (idle *idleTimeout) wrap(err error) error {
+	if idle == nil || err == nil || err == io.EOF {
+		return err
+	}
+	select {
+	case <-idle.timedOut:
+		return fmt.Errorf("no data sent or received for %v: %w", idle.timeout, err)
+	default:
+		return err
+	}
+}
+
+func (r idleReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if r.idle == nil {
+		return
+	}
+	if n > 0 {
+		r.idle.timer.Reset(r.idle.timeout)
+	}
+	err = r.idle.wrap(err)
+	return
+}
+
+func (body responseBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.done()
+	return err
+}
+
+// withOperationTimeout returns opts with the context of SetContext limited to
+// timeout, if it is positive, see OCR.OperationTimeout, the function marking
+// the error of the operation if the timeout is exceeded, and the function
+// releasing the context once the operation is done.
+func (opts baiduOCROption) withOperationTimeout(timeout time.Duration) (baiduOCROption, func(error) error, context.CancelFunc) {
+	if timeout <= 0 {
+		return opts, func(err error) error { return err }, func() {}
+	}
+	parent := opts.context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	opts.context = ctx
+	return opts, func(err error) error {
+		if err != nil && ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			err = fmt.Errorf("operation timeout exceeded: %w", err)
+		}
+		return err
+	}, cancel
+}
+
+// contextErr returns the error of the context of SetContext, if any, for the
+// steps of a call to stop when it is done.
+func (opts baiduOCROption) contextErr() error {
+	if opts.context == nil {
+		return nil
+	}
+	return opts.context.Err()
+}
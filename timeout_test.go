@@ -0,0 +1,112 @@
+package baiduocr_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestTimeoutMode(t *testing.T) {
+	var stall time.Duration
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(stall)
+		// answer slowly but steadily, 240ms in total
+		response := `{"retData":[{"word":"slow"}]}`
+		for i := 0; i < len(response); i += 4 {
+			end := i + 4
+			if end > len(response) {
+				end = len(response)
+			}
+			w.Write([]byte(response[i:end]))
+			w.(http.Flusher).Flush()
+			time.Sleep(30 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL, TimeoutInMilliseconds: 150}
+
+	if _, err := ocr.ParseJPEG(fakeJPEG); err == nil {
+		t.Error("want total timeout")
+	}
+	ocr.TimeoutMode = baiduocr.TimeoutIdle
+	if results, err := ocr.ParseJPEG(fakeJPEG); err != nil || len(results) != 1 || results[0] != "slow" {
+		t.Errorf("got %q, %v, want no idle timeout", results, err)
+	}
+	stall = 300 * time.Millisecond
+	if _, err := ocr.ParseJPEG(fakeJPEG); err == nil {
+		t.Error("want idle timeout")
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	var requests int32
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		atomic.AddInt32(&requests, 1)
+		return nil
+	})
+	ocr.OperationTimeout = 50 * time.Millisecond
+	png := encodePNG(t, image.NewGray(image.Rect(0, 0, 8, 8)))
+	slow := func(img image.Image) (image.Image, error) {
+		time.Sleep(100 * time.Millisecond)
+		return img, nil
+	}
+	var later int32
+	counted := func(img image.Image) (image.Image, error) {
+		atomic.AddInt32(&later, 1)
+		return img, nil
+	}
+	_, err := ocr.ParseImage(png, baiduocr.SetFallbackPreprocessing([]baiduocr.PreprocessFunc{slow, counted}))
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "operation timeout exceeded") {
+		t.Errorf("got %v, want the operation timeout", err)
+	}
+	if requests != 1 || later != 0 {
+		t.Errorf("got %d requests and %d later steps, want the call stopped after the slow step", requests, later)
+	}
+
+	ocr.OperationTimeout = time.Second
+	requests = 0
+	if _, err := ocr.ParseImage(png); !errors.Is(err, baiduocr.ErrNoText) || requests != 1 {
+		t.Errorf("got %v after %d requests within the timeout", err, requests)
+	}
+}
+
+func TestNetworkErrorWrapped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	ocr := baiduocr.OCR{APIKey: "test-api-key", APIPath: server.URL + "/ocr"}
+	aip := baiduocr.NewAipOCR("ak", "sk")
+	aip.TokenPath = server.URL + "/token"
+	for name, parse := range map[string]func(options ...baiduocr.BaiduOCROption) ([]string, error){
+		"/ocr": func(options ...baiduocr.BaiduOCROption) ([]string, error) {
+			return ocr.ParseJPEG(fakeJPEG, options...)
+		},
+		"/token": func(options ...baiduocr.BaiduOCROption) ([]string, error) {
+			return aip.GeneralBasic(fakeJPEG, options...)
+		},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := parse(baiduocr.SetContext(ctx))
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: got error %v, want deadline exceeded", name, err)
+		}
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) {
+			t.Errorf("%s: got error %v, want a *url.Error", name, err)
+		}
+		if want := "baiduocr: request to " + server.URL + name + " failed: "; err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%s: got error %v, want prefix %q", name, err, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package baiduocr
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Time spent in each phase of a call, see ProfileTiming. Phases skipped,
+	// such as decoding a JPEG sent as is or the network for a cached result,
+	// take zero.
+	Timing struct {
+		// Decoding the image
+		Decode time.Duration
+		// Applying the preprocessing options, such as SetColorFilter
+		Preprocess time.Duration
+		// Encoding the image to JPEG for submission
+		Encode time.Duration
+		// Requests to Baidu OCR, including retries and the access token of
+		// AipOCR, until their responses are read
+		Network time.Duration
+	}
+
+	// timing accumulates the phases of a call, which may overlap when
+	// several requests are made concurrently.
+	timing struct {
+		decode, preprocess, encode, network int64
+	}
+)
+
+// Option to measure the time spent in each phase of the call, returned in
+// the Timing of ResultMeta by the ParseDetailed methods, such as to tell slow
+// preprocessing from slow responses of Baidu. Without it, Timing is nil and
+// nothing is measured.
+func ProfileTiming() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.timing = &timing{} }}
+}
+
+// track starts measuring a phase, one of the fields of timing, which is
+// added to when the returned function is called. It does nothing if timing
+// is off.
+func (opts baiduOCROption) track(phase func(*timing) *int64) func() {
+	if opts.timing == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		atomic.AddInt64(phase(opts.timing), int64(time.Since(start)))
+	}
+}
+
+func decodePhase(t *timing) *int64     { return &t.decode }
+func preprocessPhase(t *timing) *int64 { return &t.preprocess }
+func encodePhase(t *timing) *int64     { return &t.encode }
+func networkPhase(t *timing) *int64    { return &t.network }
+
+// timingResult returns the timing measured so far, or nil if timing is off.
+func (opts baiduOCROption) timingResult() *Timing {
+	if opts.timing == nil {
+		return nil
+	}
+	return &Timing{
+		Decode:     time.Duration(atomic.LoadInt64(&opts.timing.decode)),
+		Preprocess: time.Duration(atomic.LoadInt64(&opts.timing.preprocess)),
+		Encode:     time.Duration(atomic.LoadInt64(&opts.timing.encode)),
+		Network:    time.Duration(atomic.LoadInt64(&opts.timing.network)),
+	}
+}
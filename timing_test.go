@@ -0,0 +1,53 @@
+package baiduocr_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/caiguanhao/baiduocr"
+)
+
+func TestProfileTiming(t *testing.T) {
+	ocr := newTestServer(t, func(r *http.Request) []string {
+		time.Sleep(20 * time.Millisecond)
+		return []string{"ok"}
+	})
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(src, src.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	pngBytes := encodePNG(t, src)
+
+	_, meta, err := ocr.ParseDetailed(pngBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Timing != nil {
+		t.Errorf("got %+v, want no timing without ProfileTiming", meta.Timing)
+	}
+
+	_, meta, err = ocr.ParseDetailed(pngBytes, baiduocr.ProfileTiming(), baiduocr.SetMedianFilter(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	timing := meta.Timing
+	if timing == nil {
+		t.Fatal("timing is nil")
+	}
+	if timing.Decode <= 0 || timing.Preprocess <= 0 || timing.Encode <= 0 {
+		t.Errorf("got %+v, want decode, preprocess and encode measured", *timing)
+	}
+	if timing.Network < 20*time.Millisecond {
+		t.Errorf("network = %v, want at least the 20ms of the server", timing.Network)
+	}
+
+	_, meta, err = ocr.ParseDetailed(fakeJPEG, baiduocr.ProfileTiming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing := meta.Timing; timing.Decode != 0 || timing.Encode != 0 || timing.Network <= 0 {
+		t.Errorf("got %+v, want only the network for a JPEG sent as is", *timing)
+	}
+}
@@ -0,0 +1,96 @@
+package baiduocr
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is returned with RejectInvalidUTF8 if a response is not
+// valid UTF-8; test for it with errors.Is.
+var ErrInvalidUTF8 = errors.New("BaiduOCR response is not valid UTF-8")
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Option to fail with ErrInvalidUTF8 if a response has invalid UTF-8, such
+// as mangled Chinese or Japanese text, instead of replacing each invalid
+// sequence with the replacement character U+FFFD, the default. A leading
+// byte order mark is stripped either way.
+func RejectInvalidUTF8() BaiduOCROption {
+	return BaiduOCROption{func(option *baiduOCROption) { option.rejectInvalidUTF8 = true }}
+}
+
+// decodeUTF8 returns the response body without a leading byte order mark,
+// with invalid sequences replaced or rejected as set by RejectInvalidUTF8.
+func (opts baiduOCROption) decodeUTF8(body []byte) ([]byte, error) {
+	body = bytes.TrimPrefix(body, utf8BOM)
+	if utf8.Valid(body) {
+		return body, nil
+	}
+	if opts.rejectInvalidUTF8 {
+		return nil, invalidUTF8Error(body)
+	}
+	return bytes.ToValidUTF8(body, []byte(string(utf8.RuneError))), nil
+}
+
+func invalidUTF8Error(body []byte) error {
+	offset := 0
+	for offset < len(body) {
+		r, size := utf8.DecodeRune(body[offset:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		offset += size
+	}
+	return fmt.Errorf("%w: at byte %d", ErrInvalidUTF8, offset)
+}
+
+// utf8Reader is decodeUTF8 for streamed bodies, reading rune by rune so that
+// sequences split across reads are decoded whole. Its error is kept for the
+// reads after, as json.Decoder may drop an error returned with data.
+type utf8Reader struct {
+	reader *bufio.Reader
+	reject bool
+	offset int
+	err    error
+}
+
+func (opts baiduOCROption) newUTF8Reader(reader io.Reader) *utf8Reader {
+	buffered := bufio.NewReader(reader)
+	if prefix, _ := buffered.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+	return &utf8Reader{reader: buffered, reject: opts.rejectInvalidUTF8}
+}
+
+func (reader *utf8Reader) Read(p []byte) (n int, err error) {
+	if reader.err != nil {
+		return 0, reader.err
+	}
+	defer func() { reader.err = err }()
+	for n+utf8.UTFMax <= len(p) {
+		if n > 0 && reader.reader.Buffered() == 0 {
+			// return what is read instead of blocking for more
+			return
+		}
+		var r rune
+		var size int
+		r, size, err = reader.reader.ReadRune()
+		if err != nil {
+			return
+		}
+		if r == utf8.RuneError && size == 1 && reader.reject {
+			err = fmt.Errorf("%w: at byte %d", ErrInvalidUTF8, reader.offset)
+			return
+		}
+		reader.offset += size
+		n += utf8.EncodeRune(p[n:], r)
+	}
+	if n == 0 && len(p) > 0 {
+		err = io.ErrShortBuffer
+	}
+	return
+}
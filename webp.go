@@ -0,0 +1,21 @@
+//go:build webp
+// +build webp
+
+package baiduocr
+
+import (
+	"bytes"
+	"image"
+
+	"golang.org/x/image/webp"
+)
+
+func init() {
+	imageDecoders["image/webp"] = func(imageBytes []byte, opts baiduOCROption) (image.Image, error) {
+		img, err := webp.Decode(bytes.NewReader(imageBytes))
+		if err != nil {
+			return nil, err
+		}
+		return opts.flatten(img), nil
+	}
+}